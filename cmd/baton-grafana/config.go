@@ -1,14 +1,29 @@
 package main
 
 import (
+	"github.com/conductorone/baton-grafana/pkg/connector"
 	"github.com/conductorone/baton-sdk/pkg/field"
 	"github.com/spf13/viper"
 )
 
 var (
 	Hostname = field.StringField("hostname", field.WithDescription("The Grafana hostname used to connect to the Grafana API"), field.WithDefaultValue("http://localhost:3000"))
-	Username = field.StringField("username", field.WithRequired(true), field.WithDescription("The Grafana username used to connect to the Grafana API."))
-	Password = field.StringField("password", field.WithRequired(true), field.WithDescription("The Grafana password used to connect to the Grafana API."))
+	Username = field.StringField("username", field.WithDescription("The Grafana username used to connect to the Grafana API."))
+	// The password and token are secrets, but baton-sdk v0.2.66 has no option
+	// to mark a field as secret yet; add field.WithIsSecret(true) to both once
+	// the SDK is upgraded.
+	Password = field.StringField("password", field.WithDescription("The Grafana password used to connect to the Grafana API."))
+	APIToken = field.StringField("api-token", field.WithDescription("The Grafana service account token used to connect to the Grafana API."))
+
+	IncludeInheritedDashboardPermissions = field.BoolField(
+		"include-inherited-dashboard-permissions",
+		field.WithDescription("Also sync dashboard permissions inherited from folders, instead of only explicit dashboard permissions."),
+	)
+	PasswordCharacterClasses = field.StringSliceField(
+		"password-character-classes",
+		field.WithDescription("The character classes of generated passwords: lower, upper, digit and symbol. Every password contains at least one character of each class."),
+		field.WithDefaultValue(connector.DefaultPasswordCharacterClasses),
+	)
 	// ConfigurationFields defines the external configuration required for the
 	// connector to run. Note: these fields can be marked as optional or
 	// required.
@@ -16,6 +31,9 @@ var (
 		Hostname,
 		Username,
 		Password,
+		APIToken,
+		IncludeInheritedDashboardPermissions,
+		PasswordCharacterClasses,
 	}
 
 	// FieldRelationships defines relationships between the fields listed in
@@ -24,6 +42,9 @@ var (
 	// marked as mutually exclusive from the username password pair.
 	FieldRelationships = []field.SchemaFieldRelationship{
 		field.FieldsRequiredTogether(Username, Password),
+		field.FieldsMutuallyExclusive(APIToken, Username),
+		field.FieldsMutuallyExclusive(APIToken, Password),
+		field.FieldsAtLeastOneUsed(APIToken, Username),
 	}
 
 	cfg = field.Configuration{
@@ -14,7 +14,50 @@ func TestConfigs(t *testing.T) {
 	)
 
 	testCases := []test.TestCase{
-		// Add test cases here.
+		{
+			Configs: map[string]string{
+				"username": "admin",
+				"password": "secret",
+			},
+			IsValid: true,
+			Message: "username and password",
+		},
+		{
+			Configs: map[string]string{
+				"api-token": "glsa_token",
+			},
+			IsValid: true,
+			Message: "api token",
+		},
+		{
+			Configs: map[string]string{},
+			IsValid: false,
+			Message: "no credentials",
+		},
+		{
+			Configs: map[string]string{
+				"username": "admin",
+			},
+			IsValid: false,
+			Message: "username without password",
+		},
+		{
+			Configs: map[string]string{
+				"username":  "admin",
+				"password":  "secret",
+				"api-token": "glsa_token",
+			},
+			IsValid: false,
+			Message: "api token with username and password",
+		},
+		{
+			Configs: map[string]string{
+				"password":  "secret",
+				"api-token": "glsa_token",
+			},
+			IsValid: false,
+			Message: "api token with password",
+		},
 	}
 
 	test.ExerciseTestCases(t, configurationSchema, ValidateConfig, testCases)
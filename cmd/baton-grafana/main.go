@@ -40,8 +40,11 @@ func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, e
 	hostname := v.GetString(Hostname.FieldName)
 	username := v.GetString(Username.FieldName)
 	password := v.GetString(Password.FieldName)
+	apiToken := v.GetString(APIToken.FieldName)
+	includeInheritedDashboardPermissions := v.GetBool(IncludeInheritedDashboardPermissions.FieldName)
+	passwordCharacterClasses := v.GetStringSlice(PasswordCharacterClasses.FieldName)
 
-	cb, err := connector.New(ctx, hostname, username, password)
+	cb, err := connector.New(ctx, hostname, username, password, apiToken, includeInheritedDashboardPermissions, passwordCharacterClasses)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
@@ -0,0 +1,143 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+type apiKeyBuilder struct {
+	resourceType *v2.ResourceType
+	client       *grafana.Client
+}
+
+// ResourceType returns the Baton resource type for legacy API keys.
+func (a *apiKeyBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return resourceTypeAPIKey
+}
+
+// apiKeyResource creates a Baton secret resource for a legacy Grafana API key
+// under its organization. migration is the API key migration status of the
+// organization, or nil if the Grafana version does not report it.
+func apiKeyResource(key *grafana.APIKey, parentResourceID *v2.ResourceId, migration *grafana.APIKeyMigrationStatus) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"api_key_id":  key.ID,
+		"org_id":      parentResourceID.Resource,
+		"role":        key.Role,
+		"no_expiry":   key.Expiration == nil,
+		"has_expired": key.Expiration != nil && key.Expiration.Before(time.Now()),
+	}
+	if migration != nil {
+		profile["org_migrated_to_service_accounts"] = migration.Migrated
+	}
+
+	secretTraitOptions := []rs.SecretTraitOption{
+		withSecretProfile(profile),
+	}
+	if key.Expiration != nil {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretExpiresAt(*key.Expiration))
+	}
+
+	resource, err := rs.NewSecretResource(
+		key.Name,
+		resourceTypeAPIKey,
+		key.ID,
+		secretTraitOptions,
+		rs.WithParentResourceID(parentResourceID),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// apiKeysRemoved reports whether err shows that the Grafana instance no longer
+// has the legacy API keys endpoint.
+func apiKeysRemoved(err error) bool {
+	return grafana.IsNotFound(err) || grafana.IsGone(err)
+}
+
+// List returns the legacy API keys of the parent organization. Grafana versions
+// that removed API keys are detected and yield no resources.
+func (a *apiKeyBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// API keys are only listed as children of an organization.
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	// Fetch API keys of the organization (The endpoint used in this method does not support pagination.)
+	keys, annos, err := a.client.ListAPIKeys(ctx, parentResourceID.Resource)
+	if err != nil {
+		if apiKeysRemoved(err) {
+			ctxzap.Extract(ctx).Debug(
+				"grafana-connector: legacy API keys are not available on this Grafana version, skipping",
+				zap.String("org_id", parentResourceID.Resource),
+			)
+			return nil, "", annos, nil
+		}
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list API keys under organization %s: %w", parentResourceID.Resource, err)
+	}
+
+	if len(keys) == 0 {
+		return nil, "", annos, nil
+	}
+
+	migration, err := a.migrationStatus(ctx, parentResourceID.Resource)
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	resources := make([]*v2.Resource, 0, len(keys))
+	for _, key := range keys {
+		resource, err := apiKeyResource(&key, parentResourceID, migration)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create resource for API key %s: %w", key.Name, err)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, "", annos, nil
+}
+
+// migrationStatus returns the API key migration status of the organization,
+// or nil if the Grafana version does not report it.
+func (a *apiKeyBuilder) migrationStatus(ctx context.Context, orgID string) (*grafana.APIKeyMigrationStatus, error) {
+	migration, _, err := a.client.GetAPIKeyMigrationStatus(ctx, orgID)
+	if err != nil {
+		if grafana.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("grafana-connector: failed to get API key migration status of organization %s: %w", orgID, err)
+	}
+
+	return migration, nil
+}
+
+// Entitlements returns an empty list for API keys.
+func (a *apiKeyBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants returns an empty list for API keys. Their org role is granted by the organization.
+func (a *apiKeyBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// newAPIKeyBuilder initializes a legacy API key resource type.
+func newAPIKeyBuilder(client *grafana.Client) *apiKeyBuilder {
+	return &apiKeyBuilder{
+		resourceType: resourceTypeAPIKey,
+		client:       client,
+	}
+}
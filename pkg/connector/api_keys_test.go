@@ -0,0 +1,81 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestAPIKeysRemoved(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusGone)
+		_, _ = w.Write([]byte(`{"message":"API keys are no longer supported"}`))
+	}))
+
+	org := &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "1"}
+	keys, _, _, err := newAPIKeyBuilder(client).List(context.Background(), org, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("expected removed API keys endpoint to be skipped, got error: %v", err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("expected no API keys, got %d", len(keys))
+	}
+}
+
+func TestAPIKeyMigrationStatus(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   string
+		expected interface{}
+	}{
+		{name: "not migrated", status: `{"migrated":false}`, expected: false},
+		{name: "migrated", status: `{"migrated":true}`, expected: true},
+		{name: "not reported", expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.URL.Path == "/api/auth/keys":
+					_, _ = w.Write([]byte(`[{"id":4,"name":"deploy","role":"Admin","expiration":null}]`))
+				case r.URL.Path == "/api/serviceaccounts/migrationstatus" && tc.status != "":
+					if r.Header.Get("X-Grafana-Org-Id") != "1" {
+						t.Errorf("migration status requested for org %q, expected 1", r.Header.Get("X-Grafana-Org-Id"))
+					}
+					_, _ = w.Write([]byte(tc.status))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+
+			org := &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "1"}
+			keys, _, _, err := newAPIKeyBuilder(client).List(context.Background(), org, &pagination.Token{})
+			if err != nil {
+				t.Fatalf("List returned error: %v", err)
+			}
+			if len(keys) != 1 {
+				t.Fatalf("expected 1 API key, got %d", len(keys))
+			}
+
+			secret := &v2.SecretTrait{}
+			annos := annotations.Annotations(keys[0].Annotations)
+			if ok, err := annos.Pick(secret); err != nil || !ok {
+				t.Fatal("API key has no secret trait")
+			}
+			var migrated interface{}
+			if value, ok := secret.Profile.GetFields()["org_migrated_to_service_accounts"]; ok {
+				migrated = value.AsInterface()
+			}
+			if migrated != tc.expected {
+				t.Errorf("org_migrated_to_service_accounts = %v, expected %v", migrated, tc.expected)
+			}
+		})
+	}
+}
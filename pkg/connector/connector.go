@@ -2,8 +2,10 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 
 	"github.com/conductorone/baton-grafana/pkg/grafana"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -15,14 +17,27 @@ import (
 
 // Grafana represents the Baton connector for Grafana.
 type Grafana struct {
-	client *grafana.Client
+	client          *grafana.Client
+	serviceAccounts *serviceAccountIndex
+
+	includeInheritedDashboardPermissions bool
+	passwords                            *passwordPolicy
 }
 
 // ResourceSyncers returns a list of syncers for different resource types.
 func (g *Grafana) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
 	return []connectorbuilder.ResourceSyncer{
+		newInstanceBuilder(g.client),
 		newOrgBuilder(g.client),
-		newUserBuilder(g.client),
+		newUserBuilder(g.client, g.passwords),
+		newServiceAccountBuilder(g.client),
+		newServiceAccountTokenBuilder(g.client),
+		newAPIKeyBuilder(g.client),
+		newTeamBuilder(g.client, g.serviceAccounts),
+		newFolderBuilder(g.client, g.serviceAccounts),
+		newDashboardBuilder(g.client, g.serviceAccounts, g.includeInheritedDashboardPermissions),
+		newDataSourceBuilder(g.client),
+		newRoleBuilder(g.client, g.serviceAccounts),
 	}
 }
 
@@ -35,20 +50,36 @@ func (g *Grafana) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.Rea
 func (g *Grafana) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error) {
 	return &v2.ConnectorMetadata{
 		DisplayName: "Grafana",
-		Description: "Connector syncing Grafana organizations and users to Baton",
+		Description: "Connector syncing the Grafana server admins, organizations, users, service accounts and their tokens, legacy API keys, teams, folders, dashboards, data sources and RBAC roles to Baton",
 	}, nil
 }
 
 // Validate ensures the connector is properly configured and has valid API credentials.
+// Every sync starts with Validate, so it also forgets the service accounts
+// indexed during the previous sync.
 func (g *Grafana) Validate(ctx context.Context) (annotations.Annotations, error) {
+	g.serviceAccounts.reset()
+
 	paginationOpts := grafana.PaginationVars{
 		Size: 1,
 		Page: 0,
 	}
 
 	// Get the scope of used credentials
-	_, _, err := g.client.ListOrganizations(ctx, &paginationOpts)
+	_, _, _, err := g.client.ListOrganizations(ctx, &paginationOpts)
 	if err != nil {
+		var apiErr *grafana.APIError
+		if errors.As(err, &apiErr) {
+			switch apiErr.StatusCode {
+			case http.StatusUnauthorized:
+				return nil, fmt.Errorf("grafana-connector: validate: invalid credentials: %w", err)
+			case http.StatusForbidden:
+				if g.client.UsesServiceAccountToken() {
+					return nil, fmt.Errorf("grafana-connector: validate: service account token lacks org admin permissions: %w", err)
+				}
+				return nil, fmt.Errorf("grafana-connector: validate: credentials lack Grafana server admin permissions: %w", err)
+			}
+		}
 		return nil, fmt.Errorf("grafana-connector: validate: failed to list organizations: %w", err)
 	}
 
@@ -56,15 +87,31 @@ func (g *Grafana) Validate(ctx context.Context) (annotations.Annotations, error)
 }
 
 // New initializes a new instance of the Grafana connector.
-func New(ctx context.Context, hostname, username, password string) (*Grafana, error) {
-	grafanaClient, err := grafana.NewClient(ctx, hostname, username, password)
+// Either apiToken or the username/password pair is used to authenticate.
+// If includeInheritedDashboardPermissions is set, dashboard grants also cover
+// permissions inherited from the dashboard's folder.
+func New(
+	ctx context.Context,
+	hostname, username, password, apiToken string,
+	includeInheritedDashboardPermissions bool,
+	passwordCharacterClasses []string,
+) (*Grafana, error) {
+	grafanaClient, err := grafana.NewClient(ctx, hostname, username, password, apiToken)
 	if err != nil {
 		l := ctxzap.Extract(ctx)
 		l.Error("Error creating Grafana client", zap.Error(err))
 		return nil, err
 	}
 
+	passwords, err := newPasswordPolicy(passwordCharacterClasses)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Grafana{
-		client: grafanaClient,
+		client:                               grafanaClient,
+		serviceAccounts:                      newServiceAccountIndex(grafanaClient),
+		includeInheritedDashboardPermissions: includeInheritedDashboardPermissions,
+		passwords:                            passwords,
 	}, nil
 }
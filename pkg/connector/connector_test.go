@@ -0,0 +1,38 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+)
+
+func TestValidateForbidden(t *testing.T) {
+	forbidden := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"Permission denied"}`))
+	})
+
+	testCases := []struct {
+		name      string
+		newClient func(*testing.T, http.Handler) *grafana.Client
+		expected  string
+	}{
+		{name: "basic auth", newClient: newTestClient, expected: "credentials lack Grafana server admin permissions"},
+		{name: "service account token", newClient: newTokenTestClient, expected: "service account token lacks org admin permissions"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := &Grafana{client: tc.newClient(t, forbidden)}
+
+			_, err := g.Validate(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Validate returned error %v, expected it to contain %q", err, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+type dashboardBuilder struct {
+	resourceType    *v2.ResourceType
+	client          *grafana.Client
+	serviceAccounts *serviceAccountIndex
+
+	// includeInheritedPermissions also grants permissions the dashboard
+	// inherits from its folder, instead of only its explicit ACL entries.
+	includeInheritedPermissions bool
+}
+
+// ResourceType returns the Baton resource type for dashboards.
+func (d *dashboardBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return resourceTypeDashboard
+}
+
+// dashboardResource creates a Baton resource for a Grafana dashboard under its
+// folder, or under its organization for dashboards in the "General" folder.
+func dashboardResource(dashboard *grafana.Dashboard, orgID string, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	resource, err := rs.NewResource(
+		dashboard.Title,
+		resourceTypeDashboard,
+		orgScopedID(orgID, dashboard.UID),
+		rs.WithParentResourceID(parentResourceID),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns the dashboards directly in the parent folder, or the root-level
+// dashboards of the parent organization.
+func (d *dashboardBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// Dashboards are only listed as children of an organization or a folder.
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	var orgID, folderUID string
+	switch parentResourceID.ResourceType {
+	case resourceTypeOrg.Id:
+		orgID = parentResourceID.Resource
+	case resourceTypeFolder.Id:
+		var err error
+		orgID, folderUID, err = parseOrgScopedID(parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	default:
+		return nil, "", nil, nil
+	}
+
+	// Parse pagination token. If Token is an empty string, the function returns 0.
+	bag, page, err := parsePageToken(pToken, &v2.ResourceId{ResourceType: resourceTypeDashboard.Id})
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to parse page token: %w", err)
+	}
+
+	paginationOpts := grafana.PaginationVars{
+		Size: ResourcesPageSize,
+		Page: page,
+	}
+
+	// Fetch dashboards of the organization or folder from Grafana
+	dashboards, numNextPage, annos, err := d.client.ListDashboards(ctx, orgID, folderUID, &paginationOpts)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list dashboards under %s %s: %w", parentResourceID.ResourceType, parentResourceID.Resource, err)
+	}
+
+	// Determine next page token
+	var pageToken string
+	if numNextPage > 0 {
+		pageToken = strconv.FormatUint(numNextPage, 10)
+	}
+
+	next, err := bag.NextToken(pageToken)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to generate next page token: %w", err)
+	}
+
+	resources := make([]*v2.Resource, 0, len(dashboards))
+	for _, dashboard := range dashboards {
+		resource, err := dashboardResource(&dashboard, orgID, parentResourceID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create resource for dashboard %s: %w", dashboard.Title, err)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, next, annos, nil
+}
+
+// Entitlements returns the view, edit and admin entitlements of a dashboard.
+func (d *dashboardBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return permissionEntitlements(resource, "dashboard"), "", nil, nil
+}
+
+// Grants returns the grants derived from the dashboard permissions for users, teams and built-in org roles.
+// Only explicit entries are included unless includeInheritedPermissions is set.
+func (d *dashboardBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	orgID, dashboardUID, err := parseOrgScopedID(resource.Id)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// Fetch permissions of the dashboard (The endpoint used in this method does not support pagination.)
+	permissions, annos, err := d.client.ListDashboardPermissions(ctx, orgID, dashboardUID)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list permissions of dashboard %s: %w", dashboardUID, err)
+	}
+
+	if !d.includeInheritedPermissions {
+		explicit := make([]grafana.ResourcePermission, 0, len(permissions))
+		for _, permission := range permissions {
+			if !permission.Inherited {
+				explicit = append(explicit, permission)
+			}
+		}
+		permissions = explicit
+	}
+
+	grants, err := permissionGrants(ctx, resource, orgID, permissions, d.serviceAccounts)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return grants, "", annos, nil
+}
+
+// acl returns the permission accessors of dashboards.
+func (d *dashboardBuilder) acl() permissionACL {
+	return permissionACL{
+		kind:     "dashboard",
+		resource: "dashboards",
+		list:     d.client.ListDashboardPermissions,
+		update:   d.client.UpdateDashboardPermissions,
+	}
+}
+
+// Grant gives a user, service account or team a permission on the dashboard
+// without changing the permissions of other principals.
+func (d *dashboardBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	return grantPermission(ctx, d.client, d.acl(), principal, entitlement)
+}
+
+// Revoke removes a permission of a user, service account or team from the dashboard.
+func (d *dashboardBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	return revokePermission(ctx, d.client, d.acl(), grant)
+}
+
+// newDashboardBuilder initializes a dashboard resource type.
+func newDashboardBuilder(client *grafana.Client, serviceAccounts *serviceAccountIndex, includeInheritedPermissions bool) *dashboardBuilder {
+	return &dashboardBuilder{
+		resourceType:                resourceTypeDashboard,
+		client:                      client,
+		serviceAccounts:             serviceAccounts,
+		includeInheritedPermissions: includeInheritedPermissions,
+	}
+}
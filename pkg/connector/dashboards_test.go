@@ -0,0 +1,62 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+func TestDashboardGrants(t *testing.T) {
+	testCases := []struct {
+		name             string
+		includeInherited bool
+		expected         []string
+	}{
+		{
+			name:     "explicit only",
+			expected: []string{"dashboard:1/overview:edit user:2"},
+		},
+		{
+			name:             "with inherited",
+			includeInherited: true,
+			expected:         []string{"dashboard:1/overview:edit user:2", "dashboard:1/overview:admin team:7", "dashboard:1/overview:view org:1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/dashboards/uid/overview/permissions" {
+					t.Errorf("unexpected path %q", r.URL.Path)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`[
+					{"dashboardId":3,"userId":2,"userLogin":"alice","permission":2,"permissionName":"Edit","inherited":false},
+					{"dashboardId":3,"teamId":7,"team":"SRE","permission":4,"permissionName":"Admin","inherited":true},
+					{"dashboardId":3,"role":"Viewer","permission":1,"permissionName":"View","inherited":true}
+				]`))
+			}))
+
+			dashboard := &v2.Resource{
+				Id:          &v2.ResourceId{ResourceType: resourceTypeDashboard.Id, Resource: orgScopedID("1", "overview")},
+				DisplayName: "Overview",
+			}
+
+			grants, _, _, err := newDashboardBuilder(client, nil, tc.includeInherited).Grants(context.Background(), dashboard, nil)
+			if err != nil {
+				t.Fatalf("Grants returned error: %v", err)
+			}
+
+			if len(grants) != len(tc.expected) {
+				t.Fatalf("expected grants %v, got %d", tc.expected, len(grants))
+			}
+			for i, g := range grants {
+				if got := g.Entitlement.Id + " " + g.Principal.Id.ResourceType + ":" + g.Principal.Id.Resource; got != tc.expected[i] {
+					t.Errorf("grant %d: %q, expected %q", i, got, tc.expected[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const permissionQuery = "query"
+
+var dataSourcePermissionLevels = []string{permissionQuery, permissionEdit, permissionAdmin}
+
+// dataSourcePermissionEntitlement maps a Grafana data source permission to the entitlement slug.
+var dataSourcePermissionEntitlement = map[string]string{
+	"Query": permissionQuery,
+	"Edit":  permissionEdit,
+	"Admin": permissionAdmin,
+}
+
+// errDataSourcePermissionsUnsupported is returned when provisioning data source
+// permissions on a Grafana edition without the data source permissions API. It
+// carries the Unimplemented code, so the request is not retried.
+var errDataSourcePermissionsUnsupported = status.Error(codes.Unimplemented, "grafana-connector: data source permissions are unsupported on this edition of Grafana, they require Grafana Enterprise or Cloud")
+
+type dataSourceBuilder struct {
+	resourceType *v2.ResourceType
+	client       *grafana.Client
+}
+
+// ResourceType returns the Baton resource type for data sources.
+func (d *dataSourceBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return resourceTypeDataSource
+}
+
+// dataSourceResource creates a Baton resource for a Grafana data source under its organization.
+func dataSourceResource(dataSource *grafana.DataSource, orgID string, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	resource, err := rs.NewResource(
+		dataSource.Name,
+		resourceTypeDataSource,
+		orgScopedID(orgID, dataSource.UID),
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithDescription(fmt.Sprintf("%s data source", dataSource.Type)),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns the data sources of the parent organization.
+func (d *dataSourceBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// Data sources are only listed as children of an organization.
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	// Fetch data sources of the organization (The endpoint used in this method does not support pagination.)
+	dataSources, annos, err := d.client.ListDataSources(ctx, parentResourceID.Resource)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list data sources under organization %s: %w", parentResourceID.Resource, err)
+	}
+
+	resources := make([]*v2.Resource, 0, len(dataSources))
+	for _, dataSource := range dataSources {
+		resource, err := dataSourceResource(&dataSource, parentResourceID.Resource, parentResourceID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create resource for data source %s: %w", dataSource.Name, err)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, "", annos, nil
+}
+
+// Entitlements returns the query, edit and admin entitlements of a data source.
+func (d *dataSourceBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return levelEntitlements(resource, "data source", dataSourcePermissionLevels), "", nil, nil
+}
+
+// Grants returns the grants derived from the data source permissions for users, teams and built-in org roles.
+// Data source permissions are only available on Grafana Enterprise and Cloud; on OSS editions the
+// permissions endpoint returns 404 and data sources are synced without grants.
+func (d *dataSourceBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	orgID, dataSourceUID, err := parseOrgScopedID(resource.Id)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// Fetch permissions of the data source (The endpoint used in this method does not support pagination.)
+	permissions, annos, err := d.client.ListDataSourcePermissions(ctx, orgID, dataSourceUID)
+	if err != nil {
+		if grafana.IsNotFound(err) {
+			l.Debug(
+				"grafana-connector: data source permissions are not available on this Grafana edition, skipping grants",
+				zap.String("data_source_uid", dataSourceUID),
+			)
+			return nil, "", annos, nil
+		}
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list permissions of data source %s: %w", dataSourceUID, err)
+	}
+
+	entries := make([]principalPermission, 0, len(permissions))
+	for _, permission := range permissions {
+		level, ok := dataSourcePermissionEntitlement[permission.Permission]
+		if !ok {
+			l.Debug(
+				"grafana-connector: skipping unknown data source permission",
+				zap.String("data_source_uid", dataSourceUID),
+				zap.String("permission", permission.Permission),
+			)
+			continue
+		}
+
+		entries = append(entries, principalPermission{
+			level:     level,
+			userID:    permission.UserID,
+			userLogin: permission.UserLogin,
+			teamID:    permission.TeamID,
+			team:      permission.Team,
+			role:      permission.BuiltInRole,
+			inherited: permission.IsInherited,
+			// The access control API flags service accounts itself.
+			serviceAccount: permission.IsServiceAccount,
+		})
+	}
+
+	grants, err := principalPermissionGrants(ctx, resource, orgID, entries, nil)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return grants, "", annos, nil
+}
+
+// dataSourcePermission returns the explicit permission of a principal on a data
+// source, or nil if it has none. On OSS editions errDataSourcePermissionsUnsupported is returned.
+func (d *dataSourceBuilder) dataSourcePermission(ctx context.Context, orgID, dataSourceUID string, principal permissionPrincipal) (*grafana.DataSourcePermission, annotations.Annotations, error) {
+	permissions, annos, err := d.client.ListDataSourcePermissions(ctx, orgID, dataSourceUID)
+	if err != nil {
+		if grafana.IsNotFound(err) {
+			return nil, annos, errDataSourcePermissionsUnsupported
+		}
+		return nil, annos, fmt.Errorf("grafana-connector: failed to list permissions of data source %s: %w", dataSourceUID, err)
+	}
+
+	for _, permission := range permissions {
+		if !permission.IsManaged || permission.IsInherited {
+			continue
+		}
+		if (principal.userID != 0 && permission.UserID == principal.userID) || (principal.teamID != 0 && permission.TeamID == principal.teamID) {
+			return &permission, annos, nil
+		}
+	}
+
+	return nil, annos, nil
+}
+
+// Grant gives a user, service account or team a permission on the data source,
+// replacing any other level it has on it. Data source permissions are only
+// available on Grafana Enterprise and Cloud.
+func (d *dataSourceBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	ctx = grafana.WithoutCache(ctx)
+
+	if !slices.Contains(dataSourcePermissionLevels, entitlement.Slug) {
+		return nil, fmt.Errorf("grafana-connector: unknown data source permission %q", entitlement.Slug)
+	}
+
+	orgID, dataSourceUID, err := parseOrgScopedID(entitlement.Resource.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := newPermissionPrincipal(principal.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	permission, annos, err := d.dataSourcePermission(ctx, orgID, dataSourceUID, p)
+	if err != nil {
+		return annos, err
+	}
+
+	level := titleCase(entitlement.Slug)
+	if permission != nil && permission.Permission == level {
+		annos.Update(&v2.GrantAlreadyExists{})
+		return annos, nil
+	}
+
+	annos, err = p.set(ctx, d.client, "datasources", orgID, dataSourceUID, level)
+	if err != nil {
+		if grafana.IsNotFound(err) {
+			return annos, errDataSourcePermissionsUnsupported
+		}
+		return annos, fmt.Errorf("grafana-connector: failed to grant %s permission on data source %s to %s %s: %w", entitlement.Slug, dataSourceUID, principal.Id.ResourceType, principal.Id.Resource, err)
+	}
+
+	return annos, nil
+}
+
+// Revoke removes the permission of a user, service account or team from the
+// data source if it is still at the grant's level.
+func (d *dataSourceBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	ctx = grafana.WithoutCache(ctx)
+
+	orgID, dataSourceUID, err := parseOrgScopedID(grant.Entitlement.Resource.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := newPermissionPrincipal(grant.Principal.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	permission, annos, err := d.dataSourcePermission(ctx, orgID, dataSourceUID, p)
+	if err != nil {
+		return annos, err
+	}
+
+	if permission == nil || permission.Permission != titleCase(grant.Entitlement.Slug) {
+		annos.Update(&v2.GrantAlreadyRevoked{})
+		return annos, nil
+	}
+
+	annos, err = p.set(ctx, d.client, "datasources", orgID, dataSourceUID, "")
+	if err != nil {
+		if grafana.IsNotFound(err) {
+			return annos, errDataSourcePermissionsUnsupported
+		}
+		return annos, fmt.Errorf("grafana-connector: failed to revoke %s permission on data source %s from %s %s: %w", grant.Entitlement.Slug, dataSourceUID, grant.Principal.Id.ResourceType, grant.Principal.Id.Resource, err)
+	}
+
+	return annos, nil
+}
+
+// newDataSourceBuilder initializes a data source resource type.
+func newDataSourceBuilder(client *grafana.Client) *dataSourceBuilder {
+	return &dataSourceBuilder{
+		resourceType: resourceTypeDataSource,
+		client:       client,
+	}
+}
@@ -0,0 +1,147 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDataSourceGrants(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[
+			{"userId":2,"userLogin":"alice","permission":"Query"},
+			{"teamId":7,"team":"SRE","permission":"Admin"},
+			{"builtInRole":"Editor","permission":"Edit"}
+		]`))
+	}))
+
+	ctx := context.Background()
+
+	dataSource := &v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: resourceTypeDataSource.Id, Resource: orgScopedID("1", "prom")},
+		DisplayName: "Prometheus",
+	}
+
+	grants, _, _, err := newDataSourceBuilder(client).Grants(ctx, dataSource, nil)
+	if err != nil {
+		t.Fatalf("Grants returned error: %v", err)
+	}
+
+	expected := []string{"datasource:1/prom:query", "datasource:1/prom:admin", "datasource:1/prom:edit"}
+	if len(grants) != len(expected) {
+		t.Fatalf("expected %d grants, got %d", len(expected), len(grants))
+	}
+	for i, entitlementID := range expected {
+		if grants[i].Entitlement.Id != entitlementID {
+			t.Errorf("grant %d: entitlement %q, expected %q", i, grants[i].Entitlement.Id, entitlementID)
+		}
+	}
+}
+
+func TestDataSourceGrantsOnOSS(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not found"}`))
+	}))
+
+	ctx := context.Background()
+
+	dataSource := &v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: resourceTypeDataSource.Id, Resource: orgScopedID("1", "prom")},
+		DisplayName: "Prometheus",
+	}
+
+	grants, _, _, err := newDataSourceBuilder(client).Grants(ctx, dataSource, nil)
+	if err != nil {
+		t.Fatalf("expected missing permissions API to be skipped, got error: %v", err)
+	}
+	if len(grants) != 0 {
+		t.Errorf("expected no grants, got %d", len(grants))
+	}
+}
+
+func dataSourceEntitlement(level string) *v2.Entitlement {
+	dataSource := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeDataSource.Id, Resource: orgScopedID("1", "prom")}, DisplayName: "Prometheus"}
+	return &v2.Entitlement{Id: "datasource:1:prom:" + level, Resource: dataSource, Slug: level}
+}
+
+func TestDataSourceProvisioning(t *testing.T) {
+	testCases := []struct {
+		name      string
+		revoke    bool
+		principal *v2.Resource
+		level     string
+		expected  []string
+		unchanged bool
+	}{
+		{
+			name:      "grant user",
+			principal: orgTestUser,
+			level:     permissionQuery,
+			expected:  []string{`POST /api/access-control/datasources/prom/users/2 {"permission":"Query"}`},
+		},
+		{name: "grant existing team level", principal: folderTestTeam, level: permissionAdmin, unchanged: true},
+		{
+			name:      "revoke team",
+			revoke:    true,
+			principal: folderTestTeam,
+			level:     permissionAdmin,
+			expected:  []string{`POST /api/access-control/datasources/prom/teams/7 {"permission":""}`},
+		},
+		{name: "revoke missing user", revoke: true, principal: orgTestUser, level: permissionEdit, unchanged: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var recorder requestRecorder
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if r.Method == http.MethodGet {
+					_, _ = w.Write([]byte(`[
+						{"teamId":7,"team":"SRE","permission":"Admin","isManaged":true},
+						{"builtInRole":"Editor","permission":"Edit","isManaged":true}
+					]`))
+					return
+				}
+				recorder.record(r)
+				_, _ = w.Write([]byte(`{"message":"Permission updated"}`))
+			}))
+
+			unchanged, err := provision(newDataSourceBuilder(client), tc.revoke, tc.principal, dataSourceEntitlement(tc.level))
+			assertProvisioned(t, unchanged, err, tc.unchanged)
+			recorder.assertRequests(t, tc.expected)
+		})
+	}
+}
+
+func TestDataSourceProvisioningOnOSS(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Not found"}`))
+	}))
+
+	ctx := context.Background()
+
+	builder := newDataSourceBuilder(client)
+
+	_, err := builder.Grant(ctx, orgTestUser, dataSourceEntitlement(permissionQuery))
+	if !errors.Is(err, errDataSourcePermissionsUnsupported) {
+		t.Errorf("Grant returned %v, expected %v", err, errDataSourcePermissionsUnsupported)
+	}
+	if code := status.Code(err); code != codes.Unimplemented {
+		t.Errorf("Grant returned code %s, expected %s", code, codes.Unimplemented)
+	}
+
+	_, err = builder.Revoke(ctx, &v2.Grant{Entitlement: dataSourceEntitlement(permissionQuery), Principal: orgTestUser})
+	if !errors.Is(err, errDataSourcePermissionsUnsupported) {
+		t.Errorf("Revoke returned %v, expected %v", err, errDataSourcePermissionsUnsupported)
+	}
+}
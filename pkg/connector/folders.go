@@ -0,0 +1,231 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// nestedFolderProbeUID is the UID of a folder that does not exist, used to
+// detect whether an organization supports nested folders.
+const nestedFolderProbeUID = "baton-nested-folders-probe"
+
+type folderBuilder struct {
+	resourceType    *v2.ResourceType
+	client          *grafana.Client
+	serviceAccounts *serviceAccountIndex
+
+	// nestedOrgs records for each probed organization whether its folders can
+	// be nested; they cannot when nested folders are disabled or the Grafana
+	// version predates them.
+	mu         sync.Mutex
+	nestedOrgs map[string]bool
+}
+
+// ResourceType returns the Baton resource type for folders.
+func (f *folderBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return resourceTypeFolder
+}
+
+// folderResource creates a Baton resource for a Grafana folder. Root-level
+// folders are children of their organization and nested folders are children
+// of their parent folder. Folder UIDs are only unique within an organization,
+// so the resource ID is scoped to it.
+func folderResource(folder *grafana.Folder, orgID string, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	resource, err := rs.NewResource(
+		folder.Title,
+		resourceTypeFolder,
+		orgScopedID(orgID, folder.UID),
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithAnnotation(
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeFolder.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeDashboard.Id},
+		),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns the root-level folders of the parent organization, or the
+// direct subfolders of the parent folder. Organizations without nested folders
+// are not listed for subfolders, see nested.
+func (f *folderBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// Folders are only listed as children of an organization or another folder.
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	var orgID, parentUID string
+	switch parentResourceID.ResourceType {
+	case resourceTypeOrg.Id:
+		orgID = parentResourceID.Resource
+		// Nested folders may have been enabled since the previous sync.
+		if pToken.Token == "" {
+			f.forgetNested(orgID)
+		}
+	case resourceTypeFolder.Id:
+		var err error
+		orgID, parentUID, err = parseOrgScopedID(parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		nested, annos, err := f.nested(ctx, orgID)
+		if err != nil {
+			return nil, "", annos, err
+		}
+		if !nested {
+			return nil, "", annos, nil
+		}
+	default:
+		return nil, "", nil, nil
+	}
+
+	// Parse pagination token. If Token is an empty string, the function returns 0.
+	bag, page, err := parsePageToken(pToken, &v2.ResourceId{ResourceType: resourceTypeFolder.Id})
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to parse page token: %w", err)
+	}
+
+	paginationOpts := grafana.PaginationVars{
+		Size: ResourcesPageSize,
+		Page: page,
+	}
+
+	// Fetch folders of the organization or parent folder from Grafana
+	folders, numNextPage, annos, err := f.client.ListFolders(ctx, orgID, parentUID, &paginationOpts)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list folders under %s %s: %w", parentResourceID.ResourceType, parentResourceID.Resource, err)
+	}
+
+	// Determine next page token
+	var pageToken string
+	if numNextPage > 0 {
+		pageToken = strconv.FormatUint(numNextPage, 10)
+	}
+
+	next, err := bag.NextToken(pageToken)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to generate next page token: %w", err)
+	}
+
+	resources := make([]*v2.Resource, 0, len(folders))
+	for _, folder := range folders {
+		resource, err := folderResource(&folder, orgID, parentResourceID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create resource for folder %s: %w", folder.Title, err)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, next, annos, nil
+}
+
+// nested reports whether the folders of the organization can be nested. On
+// first use it lists the subfolders of a folder that does not exist: Grafana
+// versions with nested folders find none, or respond with 404, while versions
+// without them ignore the parent and return the root-level folders.
+func (f *folderBuilder) nested(ctx context.Context, orgID string) (bool, annotations.Annotations, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if nested, ok := f.nestedOrgs[orgID]; ok {
+		return nested, nil, nil
+	}
+
+	// A response cached during the previous sync would hide newly enabled nested folders.
+	folders, _, annos, err := f.client.ListFolders(grafana.WithoutCache(ctx), orgID, nestedFolderProbeUID, &grafana.PaginationVars{Size: 1})
+	if err != nil && !grafana.IsNotFound(err) {
+		return false, annos, fmt.Errorf("grafana-connector: failed to detect nested folders in organization %s: %w", orgID, err)
+	}
+
+	nested := len(folders) == 0
+	if !nested {
+		ctxzap.Extract(ctx).Debug(
+			"grafana-connector: nested folders are not available in this organization, skipping subfolders",
+			zap.String("org_id", orgID),
+		)
+	}
+	f.nestedOrgs[orgID] = nested
+
+	return nested, annos, nil
+}
+
+// forgetNested drops the nested folder support detected for the organization.
+func (f *folderBuilder) forgetNested(orgID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.nestedOrgs, orgID)
+}
+
+// Entitlements returns the view, edit and admin entitlements of a folder.
+func (f *folderBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return permissionEntitlements(resource, "folder"), "", nil, nil
+}
+
+// Grants returns the grants derived from the folder permissions for users, teams and built-in org roles.
+// Permissions inherited from parent folders are included, since they apply to the folder as well.
+func (f *folderBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	orgID, folderUID, err := parseOrgScopedID(resource.Id)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// Fetch permissions of the folder (The endpoint used in this method does not support pagination.)
+	permissions, annos, err := f.client.ListFolderPermissions(ctx, orgID, folderUID)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list permissions of folder %s: %w", folderUID, err)
+	}
+
+	grants, err := permissionGrants(ctx, resource, orgID, permissions, f.serviceAccounts)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return grants, "", annos, nil
+}
+
+// acl returns the permission accessors of folders.
+func (f *folderBuilder) acl() permissionACL {
+	return permissionACL{
+		kind:     "folder",
+		resource: "folders",
+		list:     f.client.ListFolderPermissions,
+		update:   f.client.UpdateFolderPermissions,
+	}
+}
+
+// Grant gives a user, service account or team a permission on the folder
+// without changing the permissions of other principals.
+func (f *folderBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	return grantPermission(ctx, f.client, f.acl(), principal, entitlement)
+}
+
+// Revoke removes a permission of a user, service account or team from the folder.
+func (f *folderBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	return revokePermission(ctx, f.client, f.acl(), grant)
+}
+
+// newFolderBuilder initializes a folder resource type.
+func newFolderBuilder(client *grafana.Client, serviceAccounts *serviceAccountIndex) *folderBuilder {
+	return &folderBuilder{
+		resourceType:    resourceTypeFolder,
+		client:          client,
+		serviceAccounts: serviceAccounts,
+		nestedOrgs:      make(map[string]bool),
+	}
+}
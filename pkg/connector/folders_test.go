@@ -0,0 +1,116 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestFolderList(t *testing.T) {
+	folders := []grafana.Folder{
+		{UID: "platform", Title: "Platform"},
+		{UID: "kafka", Title: "Kafka", ParentUID: "platform"},
+		{UID: "billing", Title: "Billing"},
+	}
+
+	testCases := []struct {
+		name     string
+		nested   bool
+		noParent bool
+		expected map[string][]string
+		requests int
+	}{
+		{
+			name:     "nested folders",
+			nested:   true,
+			expected: map[string][]string{"": {"1/platform", "1/billing"}, "platform": {"1/kafka"}, "billing": nil},
+			requests: 4,
+		},
+		{
+			// Some Grafana 10 versions leave parentUid out of folder listings.
+			name:     "nested folders without parentUid",
+			nested:   true,
+			noParent: true,
+			expected: map[string][]string{"": {"1/platform", "1/billing"}, "platform": {"1/kafka"}, "billing": nil},
+			requests: 4,
+		},
+		{
+			// Without nested folders parentUid is ignored; only the probe is sent for subfolders.
+			name:     "flat folders",
+			expected: map[string][]string{"": {"1/platform", "1/kafka", "1/billing"}, "platform": nil, "kafka": nil, "billing": nil},
+			requests: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				parentUID := r.URL.Query().Get("parentUid")
+				w.Header().Set("Content-Type", "application/json")
+
+				if tc.nested && parentUID == nestedFolderProbeUID {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"message":"folder not found"}`))
+					return
+				}
+
+				response := make([]grafana.Folder, 0, len(folders))
+				for _, folder := range folders {
+					if tc.nested && folder.ParentUID != parentUID {
+						continue
+					}
+					if !tc.nested || tc.noParent {
+						folder.ParentUID = ""
+					}
+					response = append(response, folder)
+				}
+
+				_ = json.NewEncoder(w).Encode(response)
+			}))
+
+			builder := newFolderBuilder(client, nil)
+			org := &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "1"}
+
+			list := func(parent *v2.ResourceId) []string {
+				resources, _, _, err := builder.List(context.Background(), parent, &pagination.Token{})
+				if err != nil {
+					t.Fatalf("List returned error: %v", err)
+				}
+
+				var ids []string
+				for _, resource := range resources {
+					ids = append(ids, resource.Id.Resource)
+				}
+				return ids
+			}
+
+			roots := list(org)
+			assertFolderIDs(t, "root", roots, tc.expected[""])
+			for _, id := range roots {
+				_, uid, _ := parseOrgScopedID(&v2.ResourceId{ResourceType: resourceTypeFolder.Id, Resource: id})
+				children := list(&v2.ResourceId{ResourceType: resourceTypeFolder.Id, Resource: id})
+				assertFolderIDs(t, uid, children, tc.expected[uid])
+			}
+
+			if requests != tc.requests {
+				t.Errorf("sent %d folder requests, expected %d", requests, tc.requests)
+			}
+		})
+	}
+}
+
+func assertFolderIDs(t *testing.T, parent string, ids, expected []string) {
+	t.Helper()
+
+	if !slices.Equal(ids, expected) {
+		t.Errorf("%s: folders %v, expected %v", parent, ids, expected)
+	}
+}
@@ -3,6 +3,7 @@ package connector
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -13,6 +14,10 @@ import (
 
 const ResourcesPageSize uint64 = 50
 
+// orgScopedIDSeparator separates the organization ID from the object UID in
+// resource IDs of objects whose UIDs are only unique within an organization.
+const orgScopedIDSeparator = "/"
+
 func titleCase(s string) string {
 	titleCaser := cases.Title(language.English)
 
@@ -58,3 +63,44 @@ func parsePageToken(pagToken *pagination.Token, resourceID *v2.ResourceId) (*pag
 
 	return bag, page, nil
 }
+
+// parseStagedPageToken works like parsePageToken for syncs that walk several
+// paginated listings in turn. On the first call a page state is pushed for
+// every stage, with the first stage on top of the stack, so that NextToken
+// moves on to the next stage once the current one runs out of pages.
+func parseStagedPageToken(pagToken *pagination.Token, stages ...*v2.ResourceId) (*pagination.Bag, uint64, error) {
+	if pagToken.Token == "" && len(stages) > 0 {
+		bag := &pagination.Bag{}
+		for i := len(stages) - 1; i >= 0; i-- {
+			bag.Push(pagination.PageState{
+				ResourceTypeID: stages[i].ResourceType,
+				ResourceID:     stages[i].Resource,
+			})
+		}
+
+		token, err := bag.Marshal()
+		if err != nil {
+			return nil, 0, err
+		}
+		pagToken = &pagination.Token{Size: pagToken.Size, Token: token}
+	}
+
+	return parsePageToken(pagToken, stages[0])
+}
+
+// orgScopedID builds a resource ID for an object whose UID is only unique
+// within its organization, e.g. "1/abc123" for folder abc123 in org 1.
+func orgScopedID(orgID, uid string) string {
+	return orgID + orgScopedIDSeparator + uid
+}
+
+// parseOrgScopedID splits a resource ID built by orgScopedID into the
+// organization ID and the object UID.
+func parseOrgScopedID(resourceID *v2.ResourceId) (string, string, error) {
+	orgID, uid, found := strings.Cut(resourceID.Resource, orgScopedIDSeparator)
+	if !found || orgID == "" || uid == "" {
+		return "", "", fmt.Errorf("grafana-connector: invalid %s resource id %q", resourceID.ResourceType, resourceID.Resource)
+	}
+
+	return orgID, uid, nil
+}
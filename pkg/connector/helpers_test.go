@@ -0,0 +1,117 @@
+package connector
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+)
+
+// newTestClient starts a fake Grafana server with the given handler and returns
+// a client for it. The server is closed when the test ends.
+func newTestClient(t *testing.T, handler http.Handler) *grafana.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := grafana.NewClient(context.Background(), server.URL, "admin", "admin", "")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	return client
+}
+
+// newTokenTestClient is newTestClient for a client authenticating with a
+// service account token.
+func newTokenTestClient(t *testing.T, handler http.Handler) *grafana.Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := grafana.NewClient(context.Background(), server.URL, "", "", "glsa_token")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	return client
+}
+
+// requestRecorder records the mutating requests a fake Grafana server receives
+// as "METHOD path body".
+type requestRecorder struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+// record reads the body of a mutating request, records the request and returns
+// the body so the handler can decode it.
+func (r *requestRecorder) record(req *http.Request) []byte {
+	body, _ := io.ReadAll(req.Body)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, strings.TrimSpace(req.Method+" "+req.URL.Path+" "+string(body)))
+
+	return body
+}
+
+// assertRequests checks that exactly the expected requests were recorded, in order.
+func (r *requestRecorder) assertRequests(t *testing.T, expected []string) {
+	t.Helper()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !slices.Equal(r.requests, expected) {
+		t.Errorf("requests %v, expected %v", r.requests, expected)
+	}
+}
+
+// provisioner is a resource builder that grants and revokes entitlements.
+type provisioner interface {
+	Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error)
+	Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error)
+}
+
+// provision grants the entitlement to the principal, or revokes it if revoke is
+// set, and reports whether the result was annotated as already granted or
+// already revoked.
+func provision(p provisioner, revoke bool, principal *v2.Resource, entitlement *v2.Entitlement) (bool, error) {
+	ctx := context.Background()
+
+	if revoke {
+		annos, err := p.Revoke(ctx, &v2.Grant{Entitlement: entitlement, Principal: principal})
+		return annos.Contains(&v2.GrantAlreadyRevoked{}), err
+	}
+
+	annos, err := p.Grant(ctx, principal, entitlement)
+	return annos.Contains(&v2.GrantAlreadyExists{}), err
+}
+
+// assertProvisioned checks the outcome of provision: no error, and the
+// already granted or already revoked annotation only when nothing was expected to change.
+func assertProvisioned(t *testing.T, unchanged bool, err error, expectedUnchanged bool) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatalf("provisioning returned error: %v", err)
+	}
+	if unchanged != expectedUnchanged {
+		t.Errorf("unchanged annotation = %t, expected %t", unchanged, expectedUnchanged)
+	}
+}
+
+// testPrincipal returns a principal resource of the given type.
+func testPrincipal(resourceType *v2.ResourceType, id string) *v2.Resource {
+	return &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceType.Id, Resource: id}}
+}
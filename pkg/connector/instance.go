@@ -0,0 +1,221 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const (
+	// instanceID is the ID of the single Grafana instance resource.
+	instanceID = "grafana"
+
+	serverAdminEntitlement = "server_admin"
+)
+
+type instanceBuilder struct {
+	resourceType *v2.ResourceType
+	client       *grafana.Client
+}
+
+// ResourceType returns the Baton resource type for the Grafana instance.
+func (i *instanceBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return resourceTypeInstance
+}
+
+// instanceResource creates the Baton resource for the Grafana instance, which
+// holds instance-wide entitlements such as server admin.
+func instanceResource() (*v2.Resource, error) {
+	return rs.NewResource(
+		"Grafana",
+		resourceTypeInstance,
+		instanceID,
+	)
+}
+
+// List returns the Grafana instance.
+func (i *instanceBuilder) List(_ context.Context, _ *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	resource, err := instanceResource()
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to create resource for Grafana instance: %w", err)
+	}
+
+	return []*v2.Resource{resource}, "", nil, nil
+}
+
+// Entitlements returns the server admin entitlement of the Grafana instance.
+func (i *instanceBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	entitlements := []*v2.Entitlement{
+		ent.NewPermissionEntitlement(
+			resource,
+			serverAdminEntitlement,
+			ent.WithGrantableTo(resourceTypeUser),
+			ent.WithDisplayName("Grafana Server Admin"),
+			ent.WithDescription("Grafana server admin, with full access to every organization, user and setting of the instance"),
+		),
+	}
+
+	return entitlements, "", nil, nil
+}
+
+// Grants returns a server admin grant for every user that is a Grafana server admin.
+func (i *instanceBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	// Parse pagination token. If Token is an empty string, the function returns 0.
+	bag, page, err := parsePageToken(pToken, resource.Id)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to parse page token: %w", err)
+	}
+
+	paginationOpts := grafana.PaginationVars{
+		Size: ResourcesPageSize,
+		Page: page,
+	}
+
+	// Fetch a page of users from Grafana
+	users, numNextPage, annos, err := i.client.ListUsers(ctx, &paginationOpts, "")
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list users: %w", err)
+	}
+
+	// Determine next page token
+	var pageToken string
+	if numNextPage > 0 {
+		pageToken = strconv.FormatUint(numNextPage, 10)
+	}
+
+	next, err := bag.NextToken(pageToken)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to generate next page token: %w", err)
+	}
+
+	var grants []*v2.Grant
+	for _, user := range users {
+		if !user.IsServerAdmin() {
+			continue
+		}
+
+		principalID, err := rs.NewResourceID(resourceTypeUser, user.ID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to generate user resource id for %s: %w", user.Login, err)
+		}
+
+		grants = append(grants, grant.NewGrant(resource, serverAdminEntitlement, principalID))
+	}
+
+	return grants, next, annos, nil
+}
+
+// Grant makes a user a Grafana server admin.
+func (i *instanceBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	ctx = grafana.WithoutCache(ctx)
+
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		return nil, fmt.Errorf("grafana-connector: server admin can only be granted to users, not %s", principal.Id.ResourceType)
+	}
+	if entitlement.Slug != serverAdminEntitlement {
+		return nil, fmt.Errorf("grafana-connector: unknown Grafana instance entitlement %q", entitlement.Slug)
+	}
+
+	user, annos, err := i.client.GetUser(ctx, principal.Id.Resource)
+	if err != nil {
+		return annos, fmt.Errorf("grafana-connector: failed to get user %s: %w", principal.Id.Resource, err)
+	}
+	if user.IsServerAdmin() {
+		annos.Update(&v2.GrantAlreadyExists{})
+		return annos, nil
+	}
+
+	annos, err = i.client.UpdateUserServerAdmin(ctx, principal.Id.Resource, true)
+	if err != nil {
+		return annos, fmt.Errorf("grafana-connector: failed to make user %s a server admin: %w", user.Login, err)
+	}
+
+	return annos, nil
+}
+
+// Revoke removes the Grafana server admin permission of a user. It refuses to
+// revoke the connector's own credential or the last remaining server admin,
+// either of which would lock the connector or everyone out of administering
+// the instance.
+func (i *instanceBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	ctx = grafana.WithoutCache(ctx)
+
+	principal := grant.Principal
+	if principal.Id.ResourceType != resourceTypeUser.Id {
+		return nil, fmt.Errorf("grafana-connector: server admin can only be revoked from users, not %s", principal.Id.ResourceType)
+	}
+
+	user, annos, err := i.client.GetUser(ctx, principal.Id.Resource)
+	if err != nil {
+		return annos, fmt.Errorf("grafana-connector: failed to get user %s: %w", principal.Id.Resource, err)
+	}
+	if !user.IsServerAdmin() {
+		annos.Update(&v2.GrantAlreadyRevoked{})
+		return annos, nil
+	}
+
+	self, _, err := i.client.GetSignedInUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("grafana-connector: failed to get the connector's own user: %w", err)
+	}
+	if strconv.Itoa(self.ID) == principal.Id.Resource {
+		return nil, fmt.Errorf("grafana-connector: refusing to revoke server admin from %s, the user the connector authenticates as", user.Login)
+	}
+
+	hasOther, err := i.hasOtherServerAdmin(ctx, principal.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+	if !hasOther {
+		return nil, fmt.Errorf("grafana-connector: refusing to revoke server admin from %s, the last remaining Grafana server admin", user.Login)
+	}
+
+	annos, err = i.client.UpdateUserServerAdmin(ctx, principal.Id.Resource, false)
+	if err != nil {
+		return annos, fmt.Errorf("grafana-connector: failed to revoke server admin from user %s: %w", user.Login, err)
+	}
+
+	return annos, nil
+}
+
+// hasOtherServerAdmin reports whether an enabled user other than userID is a
+// Grafana server admin. Disabled admins cannot sign in, so they do not count.
+func (i *instanceBuilder) hasOtherServerAdmin(ctx context.Context, userID string) (bool, error) {
+	paginationOpts := grafana.PaginationVars{
+		Size: ResourcesPageSize,
+	}
+
+	for {
+		users, nextPage, _, err := i.client.ListUsers(ctx, &paginationOpts, "")
+		if err != nil {
+			return false, fmt.Errorf("grafana-connector: failed to list users: %w", err)
+		}
+
+		for _, user := range users {
+			if user.IsServerAdmin() && !user.IsDisabled && strconv.Itoa(user.ID) != userID {
+				return true, nil
+			}
+		}
+
+		if nextPage == 0 {
+			return false, nil
+		}
+		paginationOpts.Page = nextPage
+	}
+}
+
+// newInstanceBuilder initializes the Grafana instance resource type.
+func newInstanceBuilder(client *grafana.Client) *instanceBuilder {
+	return &instanceBuilder{
+		resourceType: resourceTypeInstance,
+		client:       client,
+	}
+}
@@ -0,0 +1,137 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestServerAdminGrants(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != grafana.SearchUsersPath {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"totalCount":3,"page":1,"perPage":50,"users":[
+			{"id":1,"login":"admin","isAdmin":true},
+			{"id":2,"login":"alice","isAdmin":false},
+			{"id":3,"login":"bob","isGrafanaAdmin":true}
+		]}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := grafana.NewClient(ctx, server.URL, "admin", "admin", "")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	instance, err := instanceResource()
+	if err != nil {
+		t.Fatalf("instanceResource returned error: %v", err)
+	}
+
+	grants, next, _, err := newInstanceBuilder(client).Grants(ctx, instance, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("Grants returned error: %v", err)
+	}
+	if next != "" {
+		t.Errorf("expected no next page, got %q", next)
+	}
+
+	expected := []string{"1", "3"}
+	if len(grants) != len(expected) {
+		t.Fatalf("expected %d grants, got %d", len(expected), len(grants))
+	}
+	for i, g := range grants {
+		if g.Entitlement.Id != "grafana_instance:grafana:server_admin" || g.Principal.Id.Resource != expected[i] {
+			t.Errorf("grant %d: %s -> %s", i, g.Entitlement.Id, g.Principal.Id.Resource)
+		}
+	}
+}
+
+// serverAdminTestServer fakes users 1 to 3, where the connector authenticates as user 1.
+type serverAdminTestServer struct {
+	requestRecorder
+
+	t        *testing.T
+	admins   []int
+	disabled []int
+}
+
+func (s *serverAdminTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	user := func(id int) grafana.User {
+		return grafana.User{ID: id, Login: []string{"", "admin", "alice", "bob"}[id], IsGrafanaAdmin: slices.Contains(s.admins, id), IsDisabled: slices.Contains(s.disabled, id)}
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/user":
+		_ = json.NewEncoder(w).Encode(user(1))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/users/") && r.URL.Path != grafana.SearchUsersPath:
+		id := map[string]int{"/api/users/1": 1, "/api/users/2": 2, "/api/users/3": 3}[r.URL.Path]
+		_ = json.NewEncoder(w).Encode(user(id))
+	case r.Method == http.MethodGet && r.URL.Path == grafana.SearchUsersPath:
+		_ = json.NewEncoder(w).Encode(grafana.UserSearchResponse{TotalCount: 3, Users: []grafana.User{user(1), user(2), user(3)}})
+	case r.Method == http.MethodPut:
+		s.record(r)
+		_, _ = w.Write([]byte(`{"message":"User permissions updated"}`))
+	default:
+		s.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestServerAdminProvisioning(t *testing.T) {
+	testCases := []struct {
+		name      string
+		admins    []int
+		disabled  []int
+		revoke    bool
+		userID    string
+		expected  []string
+		unchanged bool
+		wantErr   bool
+	}{
+		{name: "grant", admins: []int{1}, userID: "2", expected: []string{`PUT /api/admin/users/2/permissions {"isGrafanaAdmin":true}`}},
+		{name: "grant existing admin", admins: []int{1, 3}, userID: "3", unchanged: true},
+		{name: "revoke", admins: []int{1, 3}, revoke: true, userID: "3", expected: []string{`PUT /api/admin/users/3/permissions {"isGrafanaAdmin":false}`}},
+		{name: "revoke non-admin", admins: []int{1}, revoke: true, userID: "2", unchanged: true},
+		{name: "revoke own credential", admins: []int{1, 3}, revoke: true, userID: "1", wantErr: true},
+		{name: "revoke last admin", admins: []int{3}, revoke: true, userID: "3", wantErr: true},
+		{name: "revoke last enabled admin", admins: []int{2, 3}, disabled: []int{2}, revoke: true, userID: "3", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &serverAdminTestServer{t: t, admins: tc.admins, disabled: tc.disabled}
+			client := newTestClient(t, fake)
+
+			instance, err := instanceResource()
+			if err != nil {
+				t.Fatalf("instanceResource returned error: %v", err)
+			}
+			entitlement := &v2.Entitlement{Id: "grafana_instance:grafana:server_admin", Resource: instance, Slug: serverAdminEntitlement}
+
+			unchanged, err := provision(newInstanceBuilder(client), tc.revoke, testPrincipal(resourceTypeUser, tc.userID), entitlement)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				fake.assertRequests(t, nil)
+				return
+			}
+			assertProvisioned(t, unchanged, err, tc.unchanged)
+			fake.assertRequests(t, tc.expected)
+		})
+	}
+}
@@ -13,15 +13,31 @@ import (
 	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 )
 
 const (
+	roleNone   = "None"
 	roleViewer = "Viewer"
 	roleEditor = "Editor"
 	roleAdmin  = "Admin"
 )
 
-var userRoles = []string{roleViewer, roleEditor, roleAdmin}
+var userRoles = []string{roleNone, roleViewer, roleEditor, roleAdmin}
+
+// apiKeyRoleEntitlement returns the slug of the entitlement of the legacy API
+// keys with the given org role. API keys are not org members and their role
+// cannot be changed, so these entitlements are kept apart from the grantable
+// role entitlements and are not grantable themselves.
+func apiKeyRoleEntitlement(role string) string {
+	return "api_key_" + role
+}
+
+// orgMemberEntitlement is granted to every member of an organization, whatever
+// their role, so that members with roles the connector does not know are
+// still visible.
+const orgMemberEntitlement = "member"
 
 type orgBuilder struct {
 	resourceType *v2.ResourceType
@@ -38,6 +54,15 @@ func orgResource(org grafana.Organization) (*v2.Resource, error) {
 		titleCase(org.Name),
 		resourceTypeOrg,
 		org.ID,
+		rs.WithAnnotation(
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeServiceAccount.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeAPIKey.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeTeam.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeFolder.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeDashboard.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeDataSource.Id},
+			&v2.ChildResourceType{ResourceTypeId: resourceTypeRole.Id},
+		),
 	)
 
 	if err != nil {
@@ -61,9 +86,9 @@ func (o *orgBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *paginat
 	}
 
 	// Fetch organizations from Grafana
-	orgs, numNextPage, err := o.client.ListOrganizations(ctx, &paginationOpts)
+	orgs, numNextPage, annos, err := o.client.ListOrganizations(ctx, &paginationOpts)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("grafana-connector: failed to list organizations: %w", err)
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list organizations: %w", err)
 	}
 
 	// Determine next page token
@@ -89,13 +114,22 @@ func (o *orgBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *paginat
 		resources = append(resources, resource)
 	}
 
-	return resources, next, nil, nil
+	return resources, next, annos, nil
 }
 
-// Entitlements returns a slice of entitlements for possible user roles under organization (Viewer, Editor, Admin).
+// Entitlements returns the member entitlement and a slice of entitlements for possible user roles under organization (None, Viewer, Editor, Admin),
+// each with a matching entitlement for legacy API keys.
 func (o *orgBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	// Preallocate slice for efficiency
-	entitlements := make([]*v2.Entitlement, 0, len(userRoles))
+	entitlements := make([]*v2.Entitlement, 0, 2*len(userRoles)+1)
+
+	entitlements = append(entitlements, ent.NewAssignmentEntitlement(
+		resource,
+		orgMemberEntitlement,
+		ent.WithGrantableTo(resourceTypeUser, resourceTypeServiceAccount),
+		ent.WithDisplayName(fmt.Sprintf("%s Member", resource.DisplayName)),
+		ent.WithDescription(fmt.Sprintf("Member of %s Grafana organization, with any role", resource.DisplayName)),
+	))
 
 	for _, role := range userRoles {
 		// Generate display name and description
@@ -104,47 +138,398 @@ func (o *orgBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *p
 
 		// Define entitlement options
 		entitlementOptions := []ent.EntitlementOption{
-			ent.WithGrantableTo(resourceTypeUser),
+			ent.WithGrantableTo(resourceTypeUser, resourceTypeServiceAccount),
 			ent.WithDisplayName(displayName),
 			ent.WithDescription(description),
 		}
 
 		// Append new entitlement to the slice
 		entitlements = append(entitlements, ent.NewPermissionEntitlement(resource, role, entitlementOptions...))
+
+		entitlements = append(entitlements, ent.NewPermissionEntitlement(
+			resource,
+			apiKeyRoleEntitlement(role),
+			ent.WithDisplayName(fmt.Sprintf("%s API Key %s", resource.DisplayName, role)),
+			ent.WithDescription(fmt.Sprintf("Legacy API keys with the %s role in %s Grafana organization", titleCase(role), resource.DisplayName)),
+		))
 	}
 
 	return entitlements, "", nil, nil
 }
 
-// Grants returns a slice of grants for each user and their set role under organization.
-func (o *orgBuilder) Grants(ctx context.Context, parentResource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	// Fetch users under the organization (The endpoint used in this method does not support pagination.)
-	usersByOrgResponse, err := o.client.ListUsersByOrg(ctx, parentResource.Id.Resource)
+// Grants returns a slice of grants for each user, service account and legacy API key and their set role under organization.
+// Organization members are fetched page by page so memory stays bounded for very large organizations.
+// Service accounts and API keys are not part of the organization user search, so they are listed in later stages.
+func (o *orgBuilder) Grants(ctx context.Context, parentResource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	// Parse pagination token. If Token is an empty string, the function returns 0.
+	bag, page, err := parseStagedPageToken(
+		pToken,
+		&v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: parentResource.Id.Resource},
+		&v2.ResourceId{ResourceType: resourceTypeServiceAccount.Id, Resource: parentResource.Id.Resource},
+		&v2.ResourceId{ResourceType: resourceTypeAPIKey.Id, Resource: parentResource.Id.Resource},
+	)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to parse page token: %w", err)
+	}
+
+	paginationOpts := grafana.PaginationVars{
+		Size: ResourcesPageSize,
+		Page: page,
+	}
+
+	var grants []*v2.Grant
+	var numNextPage uint64
+	var annos annotations.Annotations
+
+	switch bag.ResourceTypeID() {
+	case resourceTypeServiceAccount.Id:
+		grants, numNextPage, annos, err = o.serviceAccountGrants(ctx, parentResource, &paginationOpts)
+	case resourceTypeAPIKey.Id:
+		grants, annos, err = o.apiKeyGrants(ctx, parentResource)
+	default:
+		grants, numNextPage, annos, err = o.userGrants(ctx, parentResource, &paginationOpts)
+	}
+	if err != nil {
+		return nil, "", annos, err
+	}
+
+	// Determine next page token
+	var pageToken string
+	if numNextPage > 0 {
+		pageToken = strconv.FormatUint(numNextPage, 10)
+	}
+
+	next, err := bag.NextToken(pageToken)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to generate next page token: %w", err)
+	}
+
+	return grants, next, annos, nil
+}
+
+// userGrants returns the role grants for a page of users under the organization.
+func (o *orgBuilder) userGrants(ctx context.Context, parentResource *v2.Resource, pVars *grafana.PaginationVars) ([]*v2.Grant, uint64, annotations.Annotations, error) {
+	// Fetch a page of users under the organization
+	usersByOrgResponse, numNextPage, annos, err := o.client.ListUsersByOrg(ctx, parentResource.Id.Resource, pVars)
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("grafana-connector: failed to list users under organization %s: %w", parentResource.Id.Resource, err)
+		return nil, 0, annos, fmt.Errorf("grafana-connector: failed to list users under organization %s: %w", parentResource.Id.Resource, err)
 	}
 
-	grants := make([]*v2.Grant, 0, len(usersByOrgResponse))
+	grants := make([]*v2.Grant, 0, 2*len(usersByOrgResponse))
 
 	// Iterate through users and create grants
 	for _, userByOrg := range usersByOrgResponse {
-		// Skip users with invalid roles
-		if !slices.Contains(userRoles, userByOrg.Role) {
-			continue
-		}
-
 		// Convert UserByOrg to User only when needed
 		user := userByOrg.ToUser()
 		ur, err := userResource(&user)
 		if err != nil {
-			return nil, "", nil, fmt.Errorf("failed to generate user resource for %s: %w", user.Email, err)
+			return nil, 0, nil, fmt.Errorf("failed to generate user resource for %s: %w", user.Email, err)
+		}
+
+		// Append grants to the slice
+		grants = append(grants, memberGrants(ctx, parentResource, ur.Id, userByOrg.Role)...)
+	}
+
+	return grants, numNextPage, annos, nil
+}
+
+// serviceAccountGrants returns the role grants for a page of service accounts under the organization.
+func (o *orgBuilder) serviceAccountGrants(ctx context.Context, parentResource *v2.Resource, pVars *grafana.PaginationVars) ([]*v2.Grant, uint64, annotations.Annotations, error) {
+	serviceAccounts, numNextPage, annos, err := o.client.ListServiceAccounts(ctx, parentResource.Id.Resource, pVars)
+	if err != nil {
+		// Instances without service accounts (before Grafana 9) don't have the endpoint.
+		if grafana.IsNotFound(err) {
+			return nil, 0, annos, nil
+		}
+		return nil, 0, annos, fmt.Errorf("grafana-connector: failed to list service accounts under organization %s: %w", parentResource.Id.Resource, err)
+	}
+
+	grants := make([]*v2.Grant, 0, 2*len(serviceAccounts))
+	for _, serviceAccount := range serviceAccounts {
+		principalID, err := serviceAccountResourceID(parentResource.Id.Resource, serviceAccount.ID)
+		if err != nil {
+			return nil, 0, nil, fmt.Errorf("failed to generate service account resource id for %s: %w", serviceAccount.Login, err)
+		}
+
+		grants = append(grants, memberGrants(ctx, parentResource, principalID, serviceAccount.Role)...)
+	}
+
+	return grants, numNextPage, annos, nil
+}
+
+// apiKeyGrants returns the role grants for the legacy API keys of the organization.
+// The endpoint used in this method does not support pagination.
+func (o *orgBuilder) apiKeyGrants(ctx context.Context, parentResource *v2.Resource) ([]*v2.Grant, annotations.Annotations, error) {
+	keys, annos, err := o.client.ListAPIKeys(ctx, parentResource.Id.Resource)
+	if err != nil {
+		if apiKeysRemoved(err) {
+			return nil, annos, nil
+		}
+		return nil, annos, fmt.Errorf("grafana-connector: failed to list API keys under organization %s: %w", parentResource.Id.Resource, err)
+	}
+
+	grants := make([]*v2.Grant, 0, len(keys))
+	for _, key := range keys {
+		// API keys are not org members; skip keys with invalid roles
+		if !slices.Contains(userRoles, key.Role) {
+			ctxzap.Extract(ctx).Warn(
+				"grafana-connector: skipping API key with unrecognised org role",
+				zap.String("org_id", parentResource.Id.Resource),
+				zap.Int("api_key_id", key.ID),
+				zap.String("role", key.Role),
+			)
+			continue
+		}
+
+		principalID, err := rs.NewResourceID(resourceTypeAPIKey, key.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate API key resource id for %s: %w", key.Name, err)
+		}
+
+		grants = append(grants, grant.NewGrant(parentResource, apiKeyRoleEntitlement(key.Role), principalID))
+	}
+
+	return grants, annos, nil
+}
+
+// memberGrants returns the member grant of an organization member and the grant
+// of their org role. The raw role is kept in the member grant metadata, and a
+// role the connector does not recognise is logged instead of granted.
+func memberGrants(ctx context.Context, org *v2.Resource, principalID *v2.ResourceId, role string) []*v2.Grant {
+	grants := []*v2.Grant{
+		grant.NewGrant(org, orgMemberEntitlement, principalID, grant.WithGrantMetadata(map[string]interface{}{"role": role})),
+	}
+
+	if !slices.Contains(userRoles, role) {
+		ctxzap.Extract(ctx).Warn(
+			"grafana-connector: org member has an unrecognised role, granting membership only",
+			zap.String("org_id", org.Id.Resource),
+			zap.String("principal", principalID.ResourceType+":"+principalID.Resource),
+			zap.String("role", role),
+		)
+		return grants
+	}
+
+	return append(grants, grant.NewGrant(org, role, principalID))
+}
+
+// Grant gives a user or service account the org role of the entitlement. Users
+// that are not yet members are added to the organization; members with a
+// different role have their role changed, since Grafana allows a single role
+// per organization. Granting the member entitlement adds a user with the None
+// role, or Viewer on Grafana versions without the None role, and leaves the
+// role of existing members alone.
+func (o *orgBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	ctx = grafana.WithoutCache(ctx)
+
+	l := ctxzap.Extract(ctx)
+
+	orgID := entitlement.Resource.Id.Resource
+	role := entitlement.Slug
+	if role != orgMemberEntitlement && !slices.Contains(userRoles, role) {
+		return nil, fmt.Errorf("grafana-connector: org entitlement %q cannot be granted", role)
+	}
+
+	switch principal.Id.ResourceType {
+	case resourceTypeUser.Id:
+	case resourceTypeServiceAccount.Id:
+		// Service accounts always belong to their organization; only their role changes.
+		serviceAccountID, err := principalGrafanaID(principal.Id)
+		if err != nil {
+			return nil, err
+		}
+		serviceAccount, annos, err := o.client.GetServiceAccount(ctx, orgID, serviceAccountID)
+		if err != nil {
+			return annos, fmt.Errorf("grafana-connector: failed to get service account %s in organization %s: %w", principal.Id.Resource, orgID, err)
+		}
+		if role == orgMemberEntitlement || serviceAccount.Role == role {
+			annos.Update(&v2.GrantAlreadyExists{})
+			return annos, nil
+		}
+
+		annos, err = o.client.UpdateServiceAccountRole(ctx, orgID, serviceAccountID, role)
+		if err != nil {
+			return annos, fmt.Errorf("grafana-connector: failed to set role %s for service account %s in organization %s: %w", role, principal.Id.Resource, orgID, err)
+		}
+		return annos, nil
+	default:
+		return nil, fmt.Errorf("grafana-connector: org roles can only be granted to users and service accounts, not %s", principal.Id.ResourceType)
+	}
+
+	userID := principal.Id.Resource
+	currentRole, isMember, annos, err := o.orgRole(ctx, orgID, userID)
+	if err != nil {
+		return annos, err
+	}
+
+	if !isMember {
+		// Looking up the login of a non-member requires a Grafana server admin.
+		if o.client.UsesServiceAccountToken() {
+			return annos, fmt.Errorf("grafana-connector: user %s is not a member of organization %s, and adding users to an organization is not supported with a service account token", userID, orgID)
+		}
+
+		user, annos, err := o.client.GetUser(ctx, userID)
+		if err != nil {
+			return annos, fmt.Errorf("grafana-connector: failed to get user %s: %w", userID, err)
 		}
 
-		// Append grant to the slice
-		grants = append(grants, grant.NewGrant(parentResource, userByOrg.Role, ur.Id))
+		addRole := role
+		if role == orgMemberEntitlement {
+			addRole = roleNone
+		}
+
+		annos, err = o.client.AddOrgUser(ctx, orgID, user.Login, addRole)
+		// Grafana versions before 10 have no None role; new members default to Viewer there.
+		if role == orgMemberEntitlement && grafana.IsBadRequest(err) {
+			annos, err = o.client.AddOrgUser(ctx, orgID, user.Login, roleViewer)
+		}
+		if err == nil {
+			return annos, nil
+		}
+		if !grafana.IsConflict(err) {
+			return annos, fmt.Errorf("grafana-connector: failed to add user %s to organization %s: %w", userID, orgID, err)
+		}
+		if role == orgMemberEntitlement {
+			annos.Update(&v2.GrantAlreadyExists{})
+			return annos, nil
+		}
+		// The user was added concurrently; fall through to set the role.
+	} else if role == orgMemberEntitlement || currentRole == role {
+		annos.Update(&v2.GrantAlreadyExists{})
+		return annos, nil
+	} else {
+		l.Info(
+			"grafana-connector: user is already a member of the organization with a different role, changing role",
+			zap.String("org_id", orgID),
+			zap.String("user_id", userID),
+			zap.String("current_role", currentRole),
+			zap.String("role", role),
+		)
 	}
 
-	return grants, "", nil, nil
+	annos, err = o.client.UpdateOrgUserRole(ctx, orgID, userID, role)
+	if err != nil {
+		return annos, fmt.Errorf("grafana-connector: failed to change role of user %s in organization %s to %s: %w", userID, orgID, role, err)
+	}
+
+	return annos, nil
+}
+
+// Revoke removes an org role or membership. Revoking the member or None
+// entitlement removes the user from the organization. Revoking another role
+// downgrades the member to the None role, or removes the membership on Grafana
+// versions without the None role. Service accounts can only be downgraded.
+func (o *orgBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	ctx = grafana.WithoutCache(ctx)
+
+	orgID := grant.Entitlement.Resource.Id.Resource
+	role := grant.Entitlement.Slug
+	principal := grant.Principal
+
+	switch principal.Id.ResourceType {
+	case resourceTypeUser.Id:
+	case resourceTypeServiceAccount.Id:
+		if role == orgMemberEntitlement || role == roleNone {
+			return nil, fmt.Errorf("grafana-connector: service account %s cannot be removed from its organization", principal.Id.Resource)
+		}
+		serviceAccountID, err := principalGrafanaID(principal.Id)
+		if err != nil {
+			return nil, err
+		}
+		serviceAccount, annos, err := o.client.GetServiceAccount(ctx, orgID, serviceAccountID)
+		if err != nil {
+			return annos, fmt.Errorf("grafana-connector: failed to get service account %s in organization %s: %w", principal.Id.Resource, orgID, err)
+		}
+		// Nothing to do if the service account already has another role.
+		if serviceAccount.Role != role {
+			annos.Update(&v2.GrantAlreadyRevoked{})
+			return annos, nil
+		}
+
+		annos, err = o.client.UpdateServiceAccountRole(ctx, orgID, serviceAccountID, roleNone)
+		if err != nil {
+			return annos, fmt.Errorf("grafana-connector: failed to downgrade service account %s in organization %s: %w", principal.Id.Resource, orgID, err)
+		}
+		return annos, nil
+	default:
+		return nil, fmt.Errorf("grafana-connector: org roles can only be revoked from users and service accounts, not %s", principal.Id.ResourceType)
+	}
+
+	userID := principal.Id.Resource
+	currentRole, isMember, annos, err := o.orgRole(ctx, orgID, userID)
+	if err != nil {
+		return annos, err
+	}
+
+	// Nothing to do if the user is no longer a member or already has another role.
+	if !isMember || (role != orgMemberEntitlement && currentRole != role) {
+		annos.Update(&v2.GrantAlreadyRevoked{})
+		return annos, nil
+	}
+
+	if role != orgMemberEntitlement && role != roleNone {
+		annos, err := o.client.UpdateOrgUserRole(ctx, orgID, userID, roleNone)
+		if err == nil {
+			return annos, nil
+		}
+		if !grafana.IsBadRequest(err) {
+			return annos, fmt.Errorf("grafana-connector: failed to downgrade user %s in organization %s: %w", userID, orgID, err)
+		}
+		// Grafana versions before 10 have no None role; remove the membership instead.
+	}
+
+	annos, err = o.client.RemoveOrgUser(ctx, orgID, userID)
+	if err != nil {
+		return annos, fmt.Errorf("grafana-connector: failed to remove user %s from organization %s: %w", userID, orgID, err)
+	}
+
+	return annos, nil
+}
+
+// orgRole returns the role of the user in the organization, and whether the user is a member at all.
+// Listing the organizations of a user requires a Grafana server admin, so with a service account
+// token the members of the token's organization are searched instead.
+func (o *orgBuilder) orgRole(ctx context.Context, orgID, userID string) (string, bool, annotations.Annotations, error) {
+	if o.client.UsesServiceAccountToken() {
+		return o.orgMemberRole(ctx, orgID, userID)
+	}
+
+	orgs, annos, err := o.client.ListUserOrgs(ctx, userID)
+	if err != nil {
+		return "", false, annos, fmt.Errorf("grafana-connector: failed to list organizations of user %s: %w", userID, err)
+	}
+
+	for _, org := range orgs {
+		if strconv.Itoa(org.OrgID) == orgID {
+			return org.Role, true, annos, nil
+		}
+	}
+
+	return "", false, annos, nil
+}
+
+// orgMemberRole is orgRole for clients that can only list the members of the organization.
+func (o *orgBuilder) orgMemberRole(ctx context.Context, orgID, userID string) (string, bool, annotations.Annotations, error) {
+	annos := annotations.Annotations{}
+	paginationOpts := grafana.PaginationVars{Size: ResourcesPageSize, Page: 1}
+	for {
+		members, nextPage, pageAnnos, err := o.client.ListUsersByOrg(ctx, orgID, &paginationOpts)
+		annos.Merge(pageAnnos...)
+		if err != nil {
+			return "", false, annos, fmt.Errorf("grafana-connector: failed to list members of organization %s: %w", orgID, err)
+		}
+
+		for _, member := range members {
+			if strconv.Itoa(member.ID) == userID {
+				return member.Role, true, annos, nil
+			}
+		}
+
+		if nextPage == 0 {
+			return "", false, annos, nil
+		}
+		paginationOpts.Page = nextPage
+	}
 }
 
 func newOrgBuilder(client *grafana.Client) *orgBuilder {
@@ -0,0 +1,292 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+// orgTestServer fakes the org membership endpoints for a single user (ID 2)
+// and service account (ID 9) and records the mutating requests it receives.
+type orgTestServer struct {
+	requestRecorder
+
+	role               string
+	serviceAccountRole string
+	noneRejected       bool
+}
+
+func (s *orgTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var body map[string]string
+	if r.Method != http.MethodGet {
+		_ = json.Unmarshal(s.record(r), &body)
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/users/2/orgs":
+		if s.role == "" {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]grafana.UserOrg{{OrgID: 1, Name: "Main Org.", Role: s.role}})
+	case r.Method == http.MethodGet && r.URL.Path == "/api/org/users/search":
+		response := grafana.OrgUserSearchResponse{Page: 1, PerPage: 50}
+		if s.role != "" {
+			response.TotalCount = 1
+			response.OrgUsers = []grafana.UserByOrgResponse{{ID: 2, OrgId: 1, Login: "alice", Role: s.role}}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/users/2":
+		_, _ = w.Write([]byte(`{"id":2,"login":"alice"}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/api/orgs/1/users":
+		if body["role"] == roleNone && s.noneRejected {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"Invalid role specified"}`))
+			return
+		}
+		s.role = body["role"]
+		_, _ = w.Write([]byte(`{"message":"User added to organization"}`))
+	case r.Method == http.MethodPatch && (r.URL.Path == "/api/orgs/1/users/2" || r.URL.Path == "/api/org/users/2"):
+		if body["role"] == roleNone && s.noneRejected {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"Invalid role specified"}`))
+			return
+		}
+		s.role = body["role"]
+		_, _ = w.Write([]byte(`{"message":"Organization user updated"}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/api/serviceaccounts/9":
+		_ = json.NewEncoder(w).Encode(grafana.ServiceAccount{ID: 9, Name: "ci", OrgID: 1, Role: s.serviceAccountRole})
+	case r.Method == http.MethodPatch && r.URL.Path == "/api/serviceaccounts/9":
+		s.serviceAccountRole = body["role"]
+		_, _ = w.Write([]byte(`{"message":"Service account updated"}`))
+	case r.Method == http.MethodDelete && (r.URL.Path == "/api/orgs/1/users/2" || r.URL.Path == "/api/org/users/2"):
+		s.role = ""
+		_, _ = w.Write([]byte(`{"message":"User removed from organization"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func orgEntitlement(role string) *v2.Entitlement {
+	org := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "1"}, DisplayName: "Main Org."}
+	return &v2.Entitlement{Id: "org:1:" + role, Resource: org, Slug: role}
+}
+
+var (
+	orgTestUser           = testPrincipal(resourceTypeUser, "2")
+	orgTestServiceAccount = testPrincipal(resourceTypeServiceAccount, "1/9")
+)
+
+func TestOrgGrants(t *testing.T) {
+	server := newServiceAccountTestServer(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := grafana.NewClient(ctx, server.URL, "admin", "admin", "")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	org := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "1"}, DisplayName: "Main"}
+	builder := newOrgBuilder(client)
+
+	var principals []string
+	token := &pagination.Token{}
+	for range 5 {
+		grants, next, _, err := builder.Grants(ctx, org, token)
+		if err != nil {
+			t.Fatalf("Grants returned error: %v", err)
+		}
+		for _, g := range grants {
+			principals = append(principals, g.Principal.Id.ResourceType+":"+g.Principal.Id.Resource+" "+g.Entitlement.Id)
+		}
+		if next == "" {
+			break
+		}
+		token = &pagination.Token{Token: next}
+	}
+
+	expected := []string{
+		"user:2 org:1:member", "user:2 org:1:Admin",
+		"user:3 org:1:member", "user:3 org:1:None",
+		"user:4 org:1:member",
+		"service_account:1/9 org:1:member", "service_account:1/9 org:1:Editor",
+		"api_key:4 org:1:api_key_Admin",
+	}
+	if len(principals) != len(expected) {
+		t.Fatalf("expected grants %v, got %v", expected, principals)
+	}
+	for i := range expected {
+		if principals[i] != expected[i] {
+			t.Errorf("grant %d: %q, expected %q", i, principals[i], expected[i])
+		}
+	}
+}
+
+func TestOrgProvisioning(t *testing.T) {
+	testCases := []struct {
+		name               string
+		role               string
+		serviceAccountRole string
+		noneRejected       bool
+		revoke             bool
+		principal          *v2.Resource
+		entitlement        string
+		expected           []string
+		unchanged          bool
+	}{
+		{name: "grant to non-member", entitlement: roleEditor, expected: []string{`POST /api/orgs/1/users {"loginOrEmail":"alice","role":"Editor"}`}},
+		{name: "grant different role", role: roleViewer, entitlement: roleAdmin, expected: []string{`PATCH /api/orgs/1/users/2 {"role":"Admin"}`}},
+		{name: "grant same role", role: roleAdmin, entitlement: roleAdmin, unchanged: true},
+		{name: "grant membership to non-member", entitlement: orgMemberEntitlement, expected: []string{`POST /api/orgs/1/users {"loginOrEmail":"alice","role":"None"}`}},
+		{
+			name:         "grant membership without None role",
+			noneRejected: true,
+			entitlement:  orgMemberEntitlement,
+			expected: []string{
+				`POST /api/orgs/1/users {"loginOrEmail":"alice","role":"None"}`,
+				`POST /api/orgs/1/users {"loginOrEmail":"alice","role":"Viewer"}`,
+			},
+		},
+		{name: "grant membership to member", role: roleEditor, entitlement: orgMemberEntitlement, unchanged: true},
+		{name: "revoke downgrades to None", role: roleAdmin, revoke: true, entitlement: roleAdmin, expected: []string{`PATCH /api/orgs/1/users/2 {"role":"None"}`}},
+		{
+			name:         "revoke without None role",
+			role:         roleEditor,
+			noneRejected: true,
+			revoke:       true,
+			entitlement:  roleEditor,
+			expected:     []string{`PATCH /api/orgs/1/users/2 {"role":"None"}`, `DELETE /api/orgs/1/users/2`},
+		},
+		{name: "revoke membership", role: roleViewer, revoke: true, entitlement: orgMemberEntitlement, expected: []string{`DELETE /api/orgs/1/users/2`}},
+		{name: "revoke changed role", role: roleViewer, revoke: true, entitlement: roleAdmin, unchanged: true},
+		{name: "revoke from non-member", revoke: true, entitlement: roleViewer, unchanged: true},
+		{
+			name:               "grant service account",
+			serviceAccountRole: roleViewer,
+			principal:          orgTestServiceAccount,
+			entitlement:        roleEditor,
+			expected:           []string{`PATCH /api/serviceaccounts/9 {"role":"Editor"}`},
+		},
+		{name: "grant service account same role", serviceAccountRole: roleEditor, principal: orgTestServiceAccount, entitlement: roleEditor, unchanged: true},
+		{name: "grant service account membership", serviceAccountRole: roleViewer, principal: orgTestServiceAccount, entitlement: orgMemberEntitlement, unchanged: true},
+		{
+			name:               "revoke service account",
+			serviceAccountRole: roleEditor,
+			revoke:             true,
+			principal:          orgTestServiceAccount,
+			entitlement:        roleEditor,
+			expected:           []string{`PATCH /api/serviceaccounts/9 {"role":"None"}`},
+		},
+		{
+			name:               "revoke service account changed role",
+			serviceAccountRole: roleAdmin,
+			revoke:             true,
+			principal:          orgTestServiceAccount,
+			entitlement:        roleEditor,
+			unchanged:          true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &orgTestServer{role: tc.role, serviceAccountRole: tc.serviceAccountRole, noneRejected: tc.noneRejected}
+			builder := newOrgBuilder(newTestClient(t, fake))
+
+			principal := tc.principal
+			if principal == nil {
+				principal = orgTestUser
+			}
+			unchanged, err := provision(builder, tc.revoke, principal, orgEntitlement(tc.entitlement))
+			assertProvisioned(t, unchanged, err, tc.unchanged)
+			fake.assertRequests(t, tc.expected)
+		})
+	}
+}
+
+func TestOrgProvisioningWithServiceAccountToken(t *testing.T) {
+	testCases := []struct {
+		name        string
+		role        string
+		revoke      bool
+		entitlement string
+		expected    []string
+		unchanged   bool
+	}{
+		{name: "grant different role", role: roleViewer, entitlement: roleAdmin, expected: []string{`PATCH /api/org/users/2 {"role":"Admin"}`}},
+		{name: "grant same role", role: roleAdmin, entitlement: roleAdmin, unchanged: true},
+		{name: "revoke downgrades to None", role: roleAdmin, revoke: true, entitlement: roleAdmin, expected: []string{`PATCH /api/org/users/2 {"role":"None"}`}},
+		{name: "revoke membership", role: roleViewer, revoke: true, entitlement: orgMemberEntitlement, expected: []string{`DELETE /api/org/users/2`}},
+		{name: "revoke from non-member", revoke: true, entitlement: roleViewer, unchanged: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &orgTestServer{role: tc.role}
+			builder := newOrgBuilder(newTokenTestClient(t, fake))
+
+			unchanged, err := provision(builder, tc.revoke, orgTestUser, orgEntitlement(tc.entitlement))
+			assertProvisioned(t, unchanged, err, tc.unchanged)
+			fake.assertRequests(t, tc.expected)
+		})
+	}
+
+	t.Run("grant to non-member", func(t *testing.T) {
+		fake := &orgTestServer{}
+		builder := newOrgBuilder(newTokenTestClient(t, fake))
+
+		if _, err := provision(builder, false, orgTestUser, orgEntitlement(roleEditor)); err == nil {
+			t.Fatal("expected adding a user to the organization to fail with a service account token")
+		}
+		fake.assertRequests(t, nil)
+	})
+}
+
+func TestOrgGrantsWithServiceAccountToken(t *testing.T) {
+	client := newTokenTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/org/users/search":
+			_, _ = w.Write([]byte(`{"totalCount":1,"page":1,"perPage":50,"orgUsers":[{"orgId":2,"userId":5,"login":"alice","role":"Editor"}]}`))
+		case "/api/serviceaccounts/search":
+			_, _ = w.Write([]byte(`{"totalCount":0,"page":1,"perPage":50,"serviceAccounts":[]}`))
+		case "/api/auth/keys":
+			_, _ = w.Write([]byte(`[]`))
+		default:
+			// The Orgs API of other organizations requires a server admin.
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"Permission denied"}`))
+		}
+	}))
+
+	org := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "2"}, DisplayName: "Platform"}
+	builder := newOrgBuilder(client)
+
+	var principals []string
+	token := &pagination.Token{}
+	for range 5 {
+		grants, next, _, err := builder.Grants(context.Background(), org, token)
+		if err != nil {
+			t.Fatalf("Grants returned error: %v", err)
+		}
+		for _, g := range grants {
+			principals = append(principals, g.Principal.Id.ResourceType+":"+g.Principal.Id.Resource+" "+g.Entitlement.Id)
+		}
+		if next == "" {
+			break
+		}
+		token = &pagination.Token{Token: next}
+	}
+
+	if !slices.Equal(principals, []string{"user:5 org:2:member", "user:5 org:2:Editor"}) {
+		t.Errorf("unexpected grants %v", principals)
+	}
+}
@@ -0,0 +1,115 @@
+package connector
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+)
+
+const (
+	characterClassLower  = "lower"
+	characterClassUpper  = "upper"
+	characterClassDigit  = "digit"
+	characterClassSymbol = "symbol"
+
+	// minPasswordLength is the shortest password generated, regardless of the requested length.
+	minPasswordLength = 8
+)
+
+// DefaultPasswordCharacterClasses are the character classes generated passwords are made of by default.
+var DefaultPasswordCharacterClasses = []string{characterClassLower, characterClassUpper, characterClassDigit, characterClassSymbol}
+
+// characterClassSets maps a character class to its characters.
+var characterClassSets = map[string]string{
+	characterClassLower:  "abcdefghijklmnopqrstuvwxyz",
+	characterClassUpper:  "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	characterClassDigit:  "0123456789",
+	characterClassSymbol: "!#$%&()*+,-./:;<=>?@[]^_{|}~",
+}
+
+// passwordPolicy generates the passwords of created accounts and rotated
+// credentials from a set of character classes.
+type passwordPolicy struct {
+	classes []string
+}
+
+// newPasswordPolicy returns a policy generating passwords from the given
+// character classes, or from all classes if none are given.
+func newPasswordPolicy(classes []string) (*passwordPolicy, error) {
+	policy := &passwordPolicy{}
+	for _, class := range classes {
+		class = strings.ToLower(strings.TrimSpace(class))
+		if _, ok := characterClassSets[class]; !ok {
+			return nil, fmt.Errorf("grafana-connector: unknown password character class %q, expected one of %s", class, strings.Join(DefaultPasswordCharacterClasses, ", "))
+		}
+		if !slices.Contains(policy.classes, class) {
+			policy.classes = append(policy.classes, class)
+		}
+	}
+
+	if len(policy.classes) == 0 {
+		policy.classes = DefaultPasswordCharacterClasses
+	}
+
+	return policy, nil
+}
+
+// generate returns a random password of the length requested by the
+// credential options, with at least one character of every class of the policy.
+func (p *passwordPolicy) generate(credentialOptions *v2.CredentialOptions) (string, error) {
+	randomPassword := credentialOptions.GetRandomPassword()
+	if randomPassword == nil {
+		return "", fmt.Errorf("grafana-connector: only random password credentials are supported")
+	}
+
+	length := int(randomPassword.GetLength())
+	if length < max(minPasswordLength, len(p.classes)) {
+		return "", fmt.Errorf("grafana-connector: password length must be at least %d, got %d", max(minPasswordLength, len(p.classes)), length)
+	}
+
+	var all strings.Builder
+	password := make([]byte, 0, length)
+	for _, class := range p.classes {
+		characters := characterClassSets[class]
+		all.WriteString(characters)
+
+		c, err := randomCharacter(characters)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	for len(password) < length {
+		c, err := randomCharacter(all.String())
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	// Shuffle so that the guaranteed characters are not always at the start.
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", fmt.Errorf("grafana-connector: failed to generate password: %w", err)
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+
+	return string(password), nil
+}
+
+// randomCharacter returns a random character of characters.
+func randomCharacter(characters string) (byte, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(len(characters))))
+	if err != nil {
+		return 0, fmt.Errorf("grafana-connector: failed to generate password: %w", err)
+	}
+
+	return characters[i.Int64()], nil
+}
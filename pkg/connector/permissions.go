@@ -0,0 +1,424 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	permissionView  = "view"
+	permissionEdit  = "edit"
+	permissionAdmin = "admin"
+)
+
+var permissionLevels = []string{permissionView, permissionEdit, permissionAdmin}
+
+// permissionLevelEntitlement maps a Grafana permission level to the entitlement slug.
+var permissionLevelEntitlement = map[grafana.PermissionLevel]string{
+	grafana.PermissionView:  permissionView,
+	grafana.PermissionEdit:  permissionEdit,
+	grafana.PermissionAdmin: permissionAdmin,
+}
+
+// entitlementPermissionLevel maps an entitlement slug to the Grafana permission level.
+var entitlementPermissionLevel = map[string]grafana.PermissionLevel{
+	permissionView:  grafana.PermissionView,
+	permissionEdit:  grafana.PermissionEdit,
+	permissionAdmin: grafana.PermissionAdmin,
+}
+
+// rolesIncluding lists the org roles that hold a given basic role. Grafana basic
+// roles are hierarchical, so a permission granted to Viewer also applies to
+// Editors and Admins.
+var rolesIncluding = map[string][]string{
+	roleViewer: {roleViewer, roleEditor, roleAdmin},
+	roleEditor: {roleEditor, roleAdmin},
+	roleAdmin:  {roleAdmin},
+}
+
+// permissionEntitlements returns the view, edit and admin entitlements of a
+// folder or dashboard. kind is used in descriptions, e.g. "folder".
+func permissionEntitlements(resource *v2.Resource, kind string) []*v2.Entitlement {
+	return levelEntitlements(resource, kind, permissionLevels)
+}
+
+// levelEntitlements returns a permission entitlement for each of the given levels.
+func levelEntitlements(resource *v2.Resource, kind string, levels []string) []*v2.Entitlement {
+	entitlements := make([]*v2.Entitlement, 0, len(levels))
+
+	for _, level := range levels {
+		displayName := fmt.Sprintf("%s %s %s", resource.DisplayName, titleCase(kind), titleCase(level))
+		description := fmt.Sprintf("%s permission on %s Grafana %s", titleCase(level), resource.DisplayName, kind)
+
+		entitlementOptions := []ent.EntitlementOption{
+			ent.WithGrantableTo(resourceTypeUser, resourceTypeServiceAccount, resourceTypeTeam, resourceTypeOrg),
+			ent.WithDisplayName(displayName),
+			ent.WithDescription(description),
+		}
+
+		entitlements = append(entitlements, ent.NewPermissionEntitlement(resource, level, entitlementOptions...))
+	}
+
+	return entitlements
+}
+
+// principalPermission is a permission entry resolved to its entitlement slug.
+// Exactly one of userID, teamID or role identifies the principal.
+type principalPermission struct {
+	level     string
+	userID    int
+	userLogin string
+	teamID    int
+	team      string
+	role      string
+	inherited bool
+
+	// serviceAccount is set when the source reports that userID belongs to a
+	// service account; otherwise the service account index is consulted.
+	serviceAccount bool
+}
+
+// permissionGrants converts folder or dashboard permission entries into grants.
+func permissionGrants(
+	ctx context.Context,
+	resource *v2.Resource,
+	orgID string,
+	permissions []grafana.ResourcePermission,
+	serviceAccounts *serviceAccountIndex,
+) ([]*v2.Grant, error) {
+	l := ctxzap.Extract(ctx)
+
+	entries := make([]principalPermission, 0, len(permissions))
+	for _, permission := range permissions {
+		level, ok := permissionLevelEntitlement[permission.Permission]
+		if !ok {
+			l.Debug(
+				"grafana-connector: skipping unknown permission level",
+				zap.String("resource_id", resource.Id.Resource),
+				zap.Int("permission", int(permission.Permission)),
+			)
+			continue
+		}
+
+		entries = append(entries, principalPermission{
+			level:     level,
+			userID:    permission.UserID,
+			userLogin: permission.UserLogin,
+			teamID:    permission.TeamID,
+			team:      permission.Team,
+			role:      permission.Role,
+			inherited: permission.Inherited,
+		})
+	}
+
+	return principalPermissionGrants(ctx, resource, orgID, entries, serviceAccounts)
+}
+
+// principalPermissionGrants converts resolved permission entries into grants.
+// User and service account entries are granted directly, team entries are
+// granted to the team and expanded to its members, and built-in role entries
+// are granted to the organization and expanded to every org role that includes
+// that role.
+func principalPermissionGrants(
+	ctx context.Context,
+	resource *v2.Resource,
+	orgID string,
+	permissions []principalPermission,
+	serviceAccounts *serviceAccountIndex,
+) ([]*v2.Grant, error) {
+	l := ctxzap.Extract(ctx)
+
+	grants := make([]*v2.Grant, 0, len(permissions))
+	grantIndex := make(map[string]int, len(permissions))
+
+	// addGrant skips duplicate entries, preferring an explicit entry over an
+	// inherited one for the same principal and level.
+	addGrant := func(g *v2.Grant, inherited bool) {
+		if i, ok := grantIndex[g.Id]; ok {
+			if !inherited {
+				grants[i] = g
+			}
+			return
+		}
+		grantIndex[g.Id] = len(grants)
+		grants = append(grants, g)
+	}
+
+	for _, permission := range permissions {
+		var grantOptions []grant.GrantOption
+		if permission.inherited {
+			// Inherited from a parent folder; keep the grant but mark its origin for reviewers.
+			grantOptions = append(grantOptions, grant.WithGrantMetadata(map[string]interface{}{"inherited": true}))
+		}
+
+		switch {
+		case permission.userID != 0:
+			var principalID *v2.ResourceId
+			var err error
+			if permission.serviceAccount {
+				principalID, err = serviceAccountResourceID(orgID, permission.userID)
+			} else {
+				principalID, err = serviceAccounts.principalID(ctx, orgID, permission.userID)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate user resource id for %s: %w", permission.userLogin, err)
+			}
+			addGrant(grant.NewGrant(resource, permission.level, principalID, grantOptions...), permission.inherited)
+
+		case permission.teamID != 0:
+			principalID, err := rs.NewResourceID(resourceTypeTeam, permission.teamID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate team resource id for %s: %w", permission.team, err)
+			}
+			grantOptions = append(grantOptions, grant.WithAnnotation(&v2.GrantExpandable{
+				EntitlementIds: []string{
+					fmt.Sprintf("%s:%d:%s", resourceTypeTeam.Id, permission.teamID, teamMemberEntitlement),
+				},
+			}))
+			addGrant(grant.NewGrant(resource, permission.level, principalID, grantOptions...), permission.inherited)
+
+		case permission.role != "":
+			roles, ok := rolesIncluding[permission.role]
+			if !ok {
+				l.Debug(
+					"grafana-connector: skipping permission for unknown role",
+					zap.String("resource_id", resource.Id.Resource),
+					zap.String("role", permission.role),
+				)
+				continue
+			}
+
+			// Legacy API keys hold org roles too, through their own entitlements.
+			entitlementIDs := make([]string, 0, 2*len(roles))
+			for _, role := range roles {
+				entitlementIDs = append(entitlementIDs,
+					fmt.Sprintf("%s:%s:%s", resourceTypeOrg.Id, orgID, role),
+					fmt.Sprintf("%s:%s:%s", resourceTypeOrg.Id, orgID, apiKeyRoleEntitlement(role)),
+				)
+			}
+
+			grantOptions = append(grantOptions, grant.WithAnnotation(&v2.GrantExpandable{EntitlementIds: entitlementIDs}))
+			addGrant(grant.NewGrant(
+				resource,
+				permission.level,
+				&v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: orgID},
+				grantOptions...,
+			), permission.inherited)
+		}
+	}
+
+	return grants, nil
+}
+
+// permissionACL reads and writes the permissions of a folder or dashboard.
+type permissionACL struct {
+	// kind is used in messages, e.g. "folder".
+	kind string
+	// resource is the resource kind of the access control API, e.g. "folders".
+	resource string
+
+	list   func(ctx context.Context, orgID, uid string) ([]grafana.ResourcePermission, annotations.Annotations, error)
+	update func(ctx context.Context, orgID, uid string, items []grafana.PermissionItem) (annotations.Annotations, error)
+}
+
+// permissionPrincipal identifies the user, service account or team a folder or
+// dashboard permission is provisioned for.
+type permissionPrincipal struct {
+	userID int
+	teamID int
+}
+
+// newPermissionPrincipal resolves a Baton principal to a permission principal.
+// Service accounts share the user ID space, so they are handled like users.
+func newPermissionPrincipal(principal *v2.ResourceId) (permissionPrincipal, error) {
+	grafanaID, err := principalGrafanaID(principal)
+	if err != nil {
+		return permissionPrincipal{}, err
+	}
+	id, err := strconv.Atoi(grafanaID)
+	if err != nil {
+		return permissionPrincipal{}, fmt.Errorf("grafana-connector: invalid %s id %q: %w", principal.ResourceType, principal.Resource, err)
+	}
+
+	switch principal.ResourceType {
+	case resourceTypeUser.Id, resourceTypeServiceAccount.Id:
+		return permissionPrincipal{userID: id}, nil
+	case resourceTypeTeam.Id:
+		return permissionPrincipal{teamID: id}, nil
+	default:
+		return permissionPrincipal{}, fmt.Errorf("grafana-connector: permissions can only be provisioned for users, service accounts and teams, not %s", principal.ResourceType)
+	}
+}
+
+// matches reports whether a permission entry belongs to the principal.
+func (p permissionPrincipal) matches(permission grafana.ResourcePermission) bool {
+	if p.userID != 0 {
+		return permission.UserID == p.userID
+	}
+	return permission.TeamID == p.teamID
+}
+
+// set sets the permission of the principal on a resource through the access
+// control API. resource is the resource kind, e.g. "folders", and an empty
+// permission removes the principal's permission.
+func (p permissionPrincipal) set(ctx context.Context, client *grafana.Client, resource, orgID, uid, permission string) (annotations.Annotations, error) {
+	if p.userID != 0 {
+		return client.SetUserPermission(ctx, orgID, resource, uid, strconv.Itoa(p.userID), permission)
+	}
+	return client.SetTeamPermission(ctx, orgID, resource, uid, strconv.Itoa(p.teamID), permission)
+}
+
+// principalPermissions splits the permissions of a resource into the explicit
+// entry of the principal, if any, and the remaining explicit entries. Inherited
+// entries are left out, since they are managed on the parent folder.
+func principalPermissions(permissions []grafana.ResourcePermission, principal permissionPrincipal) (*grafana.ResourcePermission, []grafana.ResourcePermission) {
+	var own *grafana.ResourcePermission
+	others := make([]grafana.ResourcePermission, 0, len(permissions))
+
+	for _, permission := range permissions {
+		if permission.Inherited {
+			continue
+		}
+		if own == nil && principal.matches(permission) {
+			own = &permission
+			continue
+		}
+		others = append(others, permission)
+	}
+
+	return own, others
+}
+
+// permissionItems converts permission entries into the items of the legacy permissions update API.
+func permissionItems(permissions []grafana.ResourcePermission) []grafana.PermissionItem {
+	items := make([]grafana.PermissionItem, 0, len(permissions)+1)
+	for _, permission := range permissions {
+		items = append(items, grafana.PermissionItem{
+			UserID:     permission.UserID,
+			TeamID:     permission.TeamID,
+			Role:       permission.Role,
+			Permission: permission.Permission,
+		})
+	}
+
+	return items
+}
+
+// grantPermission gives the principal the entitlement's permission level on a
+// folder or dashboard, replacing any other level the principal has on it.
+// Permissions of other principals are kept. The access control API is used
+// where available, falling back to rewriting the ACL through the legacy
+// permissions API on Grafana versions without it.
+func grantPermission(ctx context.Context, client *grafana.Client, acl permissionACL, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	ctx = grafana.WithoutCache(ctx)
+
+	level, ok := entitlementPermissionLevel[entitlement.Slug]
+	if !ok {
+		return nil, fmt.Errorf("grafana-connector: unknown %s permission %q", acl.kind, entitlement.Slug)
+	}
+
+	orgID, uid, err := parseOrgScopedID(entitlement.Resource.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := newPermissionPrincipal(principal.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, annos, err := acl.list(ctx, orgID, uid)
+	if err != nil {
+		return annos, fmt.Errorf("grafana-connector: failed to list permissions of %s %s: %w", acl.kind, uid, err)
+	}
+
+	own, others := principalPermissions(permissions, p)
+	if own != nil && own.Permission == level {
+		annos.Update(&v2.GrantAlreadyExists{})
+		return annos, nil
+	}
+
+	annos, err = p.set(ctx, client, acl.resource, orgID, uid, titleCase(entitlement.Slug))
+	if grafana.IsNotFound(err) {
+		ctxzap.Extract(ctx).Debug(
+			"grafana-connector: access control permissions API not available, using legacy permissions API",
+			zap.String("kind", acl.kind),
+			zap.String("uid", uid),
+		)
+
+		items := permissionItems(others)
+		items = append(items, grafana.PermissionItem{UserID: p.userID, TeamID: p.teamID, Permission: level})
+		annos, err = acl.update(ctx, orgID, uid, items)
+	}
+	if err != nil {
+		return annos, fmt.Errorf("grafana-connector: failed to grant %s permission on %s %s to %s %s: %w", entitlement.Slug, acl.kind, uid, principal.Id.ResourceType, principal.Id.Resource, err)
+	}
+
+	return annos, nil
+}
+
+// revokePermission removes the principal's explicit permission from a folder
+// or dashboard if it is still at the grant's level. Permissions of other
+// principals are kept, and permissions inherited from a parent folder must be
+// revoked on that folder.
+func revokePermission(ctx context.Context, client *grafana.Client, acl permissionACL, g *v2.Grant) (annotations.Annotations, error) {
+	ctx = grafana.WithoutCache(ctx)
+
+	level, ok := entitlementPermissionLevel[g.Entitlement.Slug]
+	if !ok {
+		return nil, fmt.Errorf("grafana-connector: unknown %s permission %q", acl.kind, g.Entitlement.Slug)
+	}
+
+	orgID, uid, err := parseOrgScopedID(g.Entitlement.Resource.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := newPermissionPrincipal(g.Principal.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, annos, err := acl.list(ctx, orgID, uid)
+	if err != nil {
+		return annos, fmt.Errorf("grafana-connector: failed to list permissions of %s %s: %w", acl.kind, uid, err)
+	}
+
+	own, others := principalPermissions(permissions, p)
+	if own == nil || own.Permission != level {
+		for _, permission := range permissions {
+			if permission.Inherited && permission.Permission == level && p.matches(permission) {
+				return annos, fmt.Errorf("grafana-connector: %s permission on %s %s is inherited from a parent folder and must be revoked there", g.Entitlement.Slug, acl.kind, uid)
+			}
+		}
+
+		annos.Update(&v2.GrantAlreadyRevoked{})
+		return annos, nil
+	}
+
+	annos, err = p.set(ctx, client, acl.resource, orgID, uid, "")
+	if grafana.IsNotFound(err) {
+		ctxzap.Extract(ctx).Debug(
+			"grafana-connector: access control permissions API not available, using legacy permissions API",
+			zap.String("kind", acl.kind),
+			zap.String("uid", uid),
+		)
+
+		annos, err = acl.update(ctx, orgID, uid, permissionItems(others))
+	}
+	if err != nil {
+		return annos, fmt.Errorf("grafana-connector: failed to revoke %s permission on %s %s from %s %s: %w", g.Entitlement.Slug, acl.kind, uid, g.Principal.Id.ResourceType, g.Principal.Id.Resource, err)
+	}
+
+	return annos, nil
+}
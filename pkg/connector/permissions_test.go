@@ -0,0 +1,243 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+)
+
+func TestPermissionGrants(t *testing.T) {
+	folder := &v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: resourceTypeFolder.Id, Resource: orgScopedID("1", "abc")},
+		DisplayName: "Platform",
+	}
+
+	permissions := []grafana.ResourcePermission{
+		{UserID: 2, UserLogin: "alice", Permission: grafana.PermissionAdmin},
+		{TeamID: 7, Team: "SRE", Permission: grafana.PermissionEdit},
+		{Role: roleEditor, Permission: grafana.PermissionView},
+		{Role: "Custom", Permission: grafana.PermissionView},
+		{UserID: 3, Permission: grafana.PermissionLevel(99)},
+	}
+
+	grants, err := permissionGrants(context.Background(), folder, "1", permissions, nil)
+	if err != nil {
+		t.Fatalf("permissionGrants returned error: %v", err)
+	}
+
+	if len(grants) != 3 {
+		t.Fatalf("expected 3 grants, got %d", len(grants))
+	}
+
+	expected := []struct {
+		entitlementID string
+		principal     string
+		expandable    []string
+	}{
+		{entitlementID: "folder:1/abc:admin", principal: "user:2"},
+		{entitlementID: "folder:1/abc:edit", principal: "team:7", expandable: []string{"team:7:member"}},
+		{entitlementID: "folder:1/abc:view", principal: "org:1", expandable: []string{"org:1:Editor", "org:1:api_key_Editor", "org:1:Admin", "org:1:api_key_Admin"}},
+	}
+
+	for i, e := range expected {
+		g := grants[i]
+		if g.Entitlement.Id != e.entitlementID {
+			t.Errorf("grant %d: entitlement %q, expected %q", i, g.Entitlement.Id, e.entitlementID)
+		}
+		if principal := g.Principal.Id.ResourceType + ":" + g.Principal.Id.Resource; principal != e.principal {
+			t.Errorf("grant %d: principal %q, expected %q", i, principal, e.principal)
+		}
+
+		annos := annotations.Annotations(g.Annotations)
+		expandable := &v2.GrantExpandable{}
+		ok, err := annos.Pick(expandable)
+		if err != nil {
+			t.Fatalf("grant %d: failed to read annotations: %v", i, err)
+		}
+		if ok != (e.expandable != nil) {
+			t.Errorf("grant %d: expandable annotation present = %t", i, ok)
+			continue
+		}
+		if ok && len(expandable.EntitlementIds) != len(e.expandable) {
+			t.Errorf("grant %d: expandable entitlements %v, expected %v", i, expandable.EntitlementIds, e.expandable)
+		}
+	}
+}
+
+func TestPermissionGrantsInherited(t *testing.T) {
+	folder := &v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: resourceTypeFolder.Id, Resource: orgScopedID("1", "kafka")},
+		DisplayName: "Kafka",
+	}
+
+	permissions := []grafana.ResourcePermission{
+		{UserID: 2, Permission: grafana.PermissionAdmin, Inherited: true},
+		{UserID: 2, Permission: grafana.PermissionAdmin},
+		{UserID: 3, Permission: grafana.PermissionView, Inherited: true},
+	}
+
+	grants, err := permissionGrants(context.Background(), folder, "1", permissions, nil)
+	if err != nil {
+		t.Fatalf("permissionGrants returned error: %v", err)
+	}
+
+	if len(grants) != 2 {
+		t.Fatalf("expected 2 grants, got %d", len(grants))
+	}
+
+	inherited := func(g *v2.Grant) bool {
+		annos := annotations.Annotations(g.Annotations)
+		md := &v2.GrantMetadata{}
+		ok, err := annos.Pick(md)
+		if err != nil || !ok {
+			return false
+		}
+		return md.Metadata.GetFields()["inherited"].GetBoolValue()
+	}
+
+	if inherited(grants[0]) {
+		t.Error("expected explicit entry to take precedence over the inherited one")
+	}
+	if !inherited(grants[1]) {
+		t.Error("expected inherited entry to be marked as inherited")
+	}
+}
+
+func TestParseOrgScopedID(t *testing.T) {
+	orgID, uid, err := parseOrgScopedID(&v2.ResourceId{ResourceType: resourceTypeFolder.Id, Resource: orgScopedID("3", "nested-uid")})
+	if err != nil {
+		t.Fatalf("parseOrgScopedID returned error: %v", err)
+	}
+	if orgID != "3" || uid != "nested-uid" {
+		t.Errorf("parseOrgScopedID = (%q, %q), expected (\"3\", \"nested-uid\")", orgID, uid)
+	}
+
+	if _, _, err := parseOrgScopedID(&v2.ResourceId{ResourceType: resourceTypeFolder.Id, Resource: "abc"}); err == nil {
+		t.Error("expected error for an id without organization")
+	}
+}
+
+// folderPermissionTestServer fakes the permission endpoints of folder "abc" in
+// org 1 and records the mutating requests it receives.
+type folderPermissionTestServer struct {
+	requestRecorder
+
+	permissions []grafana.ResourcePermission
+	legacy      bool
+}
+
+func (s *folderPermissionTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == http.MethodGet && r.URL.Path == "/api/folders/abc/permissions" {
+		_ = json.NewEncoder(w).Encode(s.permissions)
+		return
+	}
+
+	s.record(r)
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/access-control/folders/abc/") && !s.legacy:
+		_, _ = w.Write([]byte(`{"message":"Permission updated"}`))
+	case r.Method == http.MethodPost && r.URL.Path == "/api/folders/abc/permissions":
+		_, _ = w.Write([]byte(`{"message":"Folder permissions updated"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func folderEntitlement(level string) *v2.Entitlement {
+	folder := &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeFolder.Id, Resource: orgScopedID("1", "abc")}, DisplayName: "Ops"}
+	return &v2.Entitlement{Id: "folder:1:abc:" + level, Resource: folder, Slug: level}
+}
+
+var folderTestTeam = testPrincipal(resourceTypeTeam, "7")
+
+// folderTestPermissions holds an entry for a role, team 7 and an inherited one for user 2.
+var folderTestPermissions = []grafana.ResourcePermission{
+	{Role: roleViewer, Permission: grafana.PermissionView},
+	{TeamID: 7, Team: "ops", Permission: grafana.PermissionEdit},
+	{UserID: 2, UserLogin: "alice", Permission: grafana.PermissionView, Inherited: true},
+}
+
+func TestFolderProvisioning(t *testing.T) {
+	testCases := []struct {
+		name      string
+		legacy    bool
+		revoke    bool
+		principal *v2.Resource
+		level     string
+		expected  []string
+		unchanged bool
+	}{
+		{
+			name:      "grant user",
+			principal: orgTestUser,
+			level:     permissionEdit,
+			expected:  []string{`POST /api/access-control/folders/abc/users/2 {"permission":"Edit"}`},
+		},
+		{
+			name:      "grant team other level",
+			principal: folderTestTeam,
+			level:     permissionAdmin,
+			expected:  []string{`POST /api/access-control/folders/abc/teams/7 {"permission":"Admin"}`},
+		},
+		{name: "grant team same level", principal: folderTestTeam, level: permissionEdit, unchanged: true},
+		{
+			name:      "legacy grant keeps other entries",
+			legacy:    true,
+			principal: orgTestUser,
+			level:     permissionAdmin,
+			expected: []string{
+				`POST /api/access-control/folders/abc/users/2 {"permission":"Admin"}`,
+				`POST /api/folders/abc/permissions {"items":[{"role":"Viewer","permission":1},{"teamId":7,"permission":2},{"userId":2,"permission":4}]}`,
+			},
+		},
+		{
+			name:      "revoke team",
+			revoke:    true,
+			principal: folderTestTeam,
+			level:     permissionEdit,
+			expected:  []string{`POST /api/access-control/folders/abc/teams/7 {"permission":""}`},
+		},
+		{
+			name:      "legacy revoke keeps other entries",
+			legacy:    true,
+			revoke:    true,
+			principal: folderTestTeam,
+			level:     permissionEdit,
+			expected: []string{
+				`POST /api/access-control/folders/abc/teams/7 {"permission":""}`,
+				`POST /api/folders/abc/permissions {"items":[{"role":"Viewer","permission":1}]}`,
+			},
+		},
+		{name: "revoke changed level", revoke: true, principal: folderTestTeam, level: permissionView, unchanged: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &folderPermissionTestServer{permissions: folderTestPermissions, legacy: tc.legacy}
+			builder := newFolderBuilder(newTestClient(t, fake), nil)
+
+			unchanged, err := provision(builder, tc.revoke, tc.principal, folderEntitlement(tc.level))
+			assertProvisioned(t, unchanged, err, tc.unchanged)
+			fake.assertRequests(t, tc.expected)
+		})
+	}
+}
+
+func TestFolderRevokeInherited(t *testing.T) {
+	fake := &folderPermissionTestServer{permissions: folderTestPermissions}
+	builder := newFolderBuilder(newTestClient(t, fake), nil)
+
+	if _, err := provision(builder, true, orgTestUser, folderEntitlement(permissionView)); err == nil {
+		t.Fatal("Revoke returned no error for an inherited permission")
+	}
+	fake.assertRequests(t, nil)
+}
@@ -5,6 +5,10 @@ import (
 )
 
 var (
+	resourceTypeInstance = &v2.ResourceType{
+		Id:          "grafana_instance",
+		DisplayName: "Grafana Instance",
+	}
 	resourceTypeOrg = &v2.ResourceType{
 		Id:          "org",
 		DisplayName: "Organization",
@@ -15,4 +19,41 @@ var (
 		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
 		Annotations: annotationsForUserResourceType(),
 	}
+	resourceTypeTeam = &v2.ResourceType{
+		Id:          "team",
+		DisplayName: "Team",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
+	}
+	resourceTypeFolder = &v2.ResourceType{
+		Id:          "folder",
+		DisplayName: "Folder",
+	}
+	resourceTypeDashboard = &v2.ResourceType{
+		Id:          "dashboard",
+		DisplayName: "Dashboard",
+	}
+	resourceTypeServiceAccount = &v2.ResourceType{
+		Id:          "service_account",
+		DisplayName: "Service Account",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
+	}
+	resourceTypeServiceAccountToken = &v2.ResourceType{
+		Id:          "service_account_token",
+		DisplayName: "Service Account Token",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_SECRET},
+	}
+	resourceTypeAPIKey = &v2.ResourceType{
+		Id:          "api_key",
+		DisplayName: "API Key",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_SECRET},
+	}
+	resourceTypeRole = &v2.ResourceType{
+		Id:          "role",
+		DisplayName: "Role",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_ROLE},
+	}
+	resourceTypeDataSource = &v2.ResourceType{
+		Id:          "datasource",
+		DisplayName: "Data Source",
+	}
 )
@@ -0,0 +1,356 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const roleAssignedEntitlement = "assigned"
+
+type roleBuilder struct {
+	resourceType    *v2.ResourceType
+	client          *grafana.Client
+	serviceAccounts *serviceAccountIndex
+
+	// permissions holds the permissions of the roles fetched so far, by
+	// roleVersionKey. A role version never changes, and global roles are the
+	// same in every organization, so each is fetched once.
+	mu          sync.Mutex
+	permissions map[string][]grafana.RolePermission
+}
+
+// ResourceType returns the Baton resource type for RBAC roles.
+func (r *roleBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return resourceTypeRole
+}
+
+// roleResource creates a Baton resource for a Grafana RBAC role under the
+// organization it was listed in. Role assignments are per organization, so the
+// resource ID is scoped to it even for global fixed and basic roles.
+func roleResource(role *grafana.Role, orgID string, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	permissions := make([]interface{}, 0, len(role.Permissions))
+	for _, permission := range role.Permissions {
+		permissions = append(permissions, map[string]interface{}{
+			"action": permission.Action,
+			"scope":  permission.Scope,
+		})
+	}
+
+	profile := map[string]interface{}{
+		"role_uid":    role.UID,
+		"name":        role.Name,
+		"group":       role.Group,
+		"global":      role.Global,
+		"permissions": permissions,
+	}
+
+	displayName := role.DisplayName
+	if displayName == "" {
+		displayName = role.Name
+	}
+
+	resource, err := rs.NewRoleResource(
+		displayName,
+		resourceTypeRole,
+		orgScopedID(orgID, role.UID),
+		[]rs.RoleTraitOption{rs.WithRoleProfile(profile)},
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithDescription(role.Description),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns the RBAC roles of the parent organization. RBAC is only available
+// on Grafana Enterprise and Cloud; on OSS editions no roles are returned. The
+// role list may leave out permissions, see rolePermissions.
+func (r *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// Roles are only listed as children of an organization.
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	// Fetch roles of the organization (The endpoint used in this method does not support pagination.)
+	roles, annos, err := r.client.ListRoles(ctx, parentResourceID.Resource)
+	if err != nil {
+		if grafana.IsNotFound(err) {
+			ctxzap.Extract(ctx).Debug(
+				"grafana-connector: RBAC roles are not available on this Grafana edition, skipping",
+				zap.String("org_id", parentResourceID.Resource),
+			)
+			return nil, "", annos, nil
+		}
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list roles under organization %s: %w", parentResourceID.Resource, err)
+	}
+
+	resources := make([]*v2.Resource, 0, len(roles))
+	for _, role := range roles {
+		permissions, found, roleAnnos, err := r.rolePermissions(ctx, parentResourceID.Resource, &role)
+		annos.Merge(roleAnnos...)
+		if err != nil {
+			return nil, "", annos, err
+		}
+		// The role may have been deleted since it was listed.
+		if !found {
+			continue
+		}
+		role.Permissions = permissions
+
+		resource, err := roleResource(&role, parentResourceID.Resource, parentResourceID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create resource for role %s: %w", role.Name, err)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, "", annos, nil
+}
+
+// roleVersionKey identifies a version of a role. Global roles share the key
+// across organizations.
+func roleVersionKey(orgID string, role *grafana.Role) string {
+	key := fmt.Sprintf("%s@%d", role.UID, role.Version)
+	if role.Global {
+		return key
+	}
+	return orgScopedID(orgID, key)
+}
+
+// rolePermissions returns the permissions of a listed role, and whether the
+// role still exists. Permissions in the listing are used as they are; otherwise
+// the role is fetched, unless the same version was fetched before.
+func (r *roleBuilder) rolePermissions(ctx context.Context, orgID string, role *grafana.Role) ([]grafana.RolePermission, bool, annotations.Annotations, error) {
+	if len(role.Permissions) > 0 {
+		return role.Permissions, true, nil, nil
+	}
+
+	// Without a version in the listing a fetched role cannot be told apart from a later version.
+	key := ""
+	if role.Version > 0 {
+		key = roleVersionKey(orgID, role)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if permissions, ok := r.permissions[key]; ok && key != "" {
+		return permissions, true, nil, nil
+	}
+
+	fetched, annos, err := r.client.GetRole(ctx, orgID, role.UID)
+	if err != nil {
+		if grafana.IsNotFound(err) {
+			return nil, false, annos, nil
+		}
+		return nil, false, annos, fmt.Errorf("grafana-connector: failed to get role %s under organization %s: %w", role.UID, orgID, err)
+	}
+
+	if key != "" {
+		r.permissions[key] = fetched.Permissions
+	}
+
+	return fetched.Permissions, true, annos, nil
+}
+
+// Entitlements returns the assigned entitlement of a role.
+func (r *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	entitlements := []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(
+			resource,
+			roleAssignedEntitlement,
+			ent.WithGrantableTo(resourceTypeUser, resourceTypeServiceAccount, resourceTypeTeam, resourceTypeOrg),
+			ent.WithDisplayName(fmt.Sprintf("%s Role Assigned", resource.DisplayName)),
+			ent.WithDescription(fmt.Sprintf("Assigned the %s Grafana role", resource.DisplayName)),
+		),
+	}
+
+	return entitlements, "", nil, nil
+}
+
+// Grants returns the grants derived from the role assignments to users, service accounts, teams and built-in org roles.
+func (r *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	orgID, roleUID, err := parseOrgScopedID(resource.Id)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// Fetch assignments of the role (The endpoint used in this method does not support pagination.)
+	assignments, annos, err := r.client.ListRoleAssignments(ctx, orgID, roleUID)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list assignments of role %s: %w", roleUID, err)
+	}
+
+	entries := make([]principalPermission, 0, len(assignments.Users)+len(assignments.ServiceAccounts)+len(assignments.Teams)+len(assignments.BuiltInRoles))
+	for _, user := range assignments.Users {
+		entries = append(entries, principalPermission{level: roleAssignedEntitlement, userID: user.UserID})
+	}
+	for _, serviceAccount := range assignments.ServiceAccounts {
+		entries = append(entries, principalPermission{level: roleAssignedEntitlement, userID: serviceAccount.ServiceAccountID, serviceAccount: true})
+	}
+	for _, team := range assignments.Teams {
+		entries = append(entries, principalPermission{level: roleAssignedEntitlement, teamID: team.TeamID})
+	}
+	for _, builtInRole := range assignments.BuiltInRoles {
+		entries = append(entries, principalPermission{level: roleAssignedEntitlement, role: builtInRole.BuiltInRole})
+	}
+
+	grants, err := principalPermissionGrants(ctx, resource, orgID, entries, r.serviceAccounts)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return grants, "", annos, nil
+}
+
+// roleAssignment reports whether the role is assigned to the principal in the
+// organization, and whether that assignment is global rather than per organization.
+func (r *roleBuilder) roleAssignment(ctx context.Context, orgID, roleUID string, principal *v2.ResourceId) (bool, bool, annotations.Annotations, error) {
+	grafanaID, err := principalGrafanaID(principal)
+	if err != nil {
+		return false, false, nil, err
+	}
+	id, err := strconv.Atoi(grafanaID)
+	if err != nil {
+		return false, false, nil, fmt.Errorf("grafana-connector: invalid %s id %q: %w", principal.ResourceType, principal.Resource, err)
+	}
+
+	assignments, annos, err := r.client.ListRoleAssignments(ctx, orgID, roleUID)
+	if err != nil {
+		return false, false, annos, fmt.Errorf("grafana-connector: failed to list assignments of role %s: %w", roleUID, err)
+	}
+
+	switch principal.ResourceType {
+	case resourceTypeUser.Id:
+		assigned, global := false, false
+		for _, user := range assignments.Users {
+			if user.UserID != id {
+				continue
+			}
+			if !user.Global {
+				return true, false, annos, nil
+			}
+			assigned, global = true, true
+		}
+		return assigned, global, annos, nil
+
+	case resourceTypeServiceAccount.Id:
+		for _, serviceAccount := range assignments.ServiceAccounts {
+			if serviceAccount.ServiceAccountID == id {
+				return true, false, annos, nil
+			}
+		}
+
+	case resourceTypeTeam.Id:
+		for _, team := range assignments.Teams {
+			if team.TeamID == id {
+				return true, false, annos, nil
+			}
+		}
+
+	default:
+		return false, false, annos, fmt.Errorf("grafana-connector: roles can only be assigned to users, service accounts and teams, not %s", principal.ResourceType)
+	}
+
+	return false, false, annos, nil
+}
+
+// Grant assigns the role to a user, service account or team in the role's
+// organization. Other roles of the principal are left untouched.
+func (r *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	ctx = grafana.WithoutCache(ctx)
+
+	orgID, roleUID, err := parseOrgScopedID(entitlement.Resource.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	assigned, _, annos, err := r.roleAssignment(ctx, orgID, roleUID, principal.Id)
+	if err != nil {
+		return annos, err
+	}
+	if assigned {
+		annos.Update(&v2.GrantAlreadyExists{})
+		return annos, nil
+	}
+
+	principalID, err := principalGrafanaID(principal.Id)
+	if err != nil {
+		return annos, err
+	}
+	if principal.Id.ResourceType == resourceTypeTeam.Id {
+		annos, err = r.client.AddTeamRole(ctx, orgID, principalID, roleUID)
+	} else {
+		annos, err = r.client.AddUserRole(ctx, orgID, principalID, roleUID)
+	}
+	if err != nil {
+		return annos, fmt.Errorf("grafana-connector: failed to assign role %s to %s %s: %w", roleUID, principal.Id.ResourceType, principal.Id.Resource, err)
+	}
+
+	return annos, nil
+}
+
+// Revoke removes the assignment of the role from a user, service account or
+// team in the role's organization. Global assignments apply to every
+// organization and are not revoked.
+func (r *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	ctx = grafana.WithoutCache(ctx)
+
+	principal := grant.Principal
+	orgID, roleUID, err := parseOrgScopedID(grant.Entitlement.Resource.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	assigned, global, annos, err := r.roleAssignment(ctx, orgID, roleUID, principal.Id)
+	if err != nil {
+		return annos, err
+	}
+	if !assigned {
+		annos.Update(&v2.GrantAlreadyRevoked{})
+		return annos, nil
+	}
+	if global {
+		return annos, fmt.Errorf("grafana-connector: role %s is assigned to %s %s globally and cannot be revoked per organization", roleUID, principal.Id.ResourceType, principal.Id.Resource)
+	}
+
+	principalID, err := principalGrafanaID(principal.Id)
+	if err != nil {
+		return annos, err
+	}
+	if principal.Id.ResourceType == resourceTypeTeam.Id {
+		annos, err = r.client.RemoveTeamRole(ctx, orgID, principalID, roleUID)
+	} else {
+		annos, err = r.client.RemoveUserRole(ctx, orgID, principalID, roleUID)
+	}
+	if err != nil {
+		return annos, fmt.Errorf("grafana-connector: failed to remove role %s from %s %s: %w", roleUID, principal.Id.ResourceType, principal.Id.Resource, err)
+	}
+
+	return annos, nil
+}
+
+// newRoleBuilder initializes an RBAC role resource type.
+func newRoleBuilder(client *grafana.Client, serviceAccounts *serviceAccountIndex) *roleBuilder {
+	return &roleBuilder{
+		resourceType:    resourceTypeRole,
+		client:          client,
+		serviceAccounts: serviceAccounts,
+		permissions:     make(map[string][]grafana.RolePermission),
+	}
+}
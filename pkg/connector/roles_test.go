@@ -0,0 +1,253 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestRoleGrants(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/access-control/roles/custom_reader/assignments":
+			_, _ = w.Write([]byte(`{
+				"roleUID":"custom_reader",
+				"users":[{"userId":2}],
+				"serviceAccounts":[{"serviceAccountId":9}],
+				"teams":[{"teamId":7}],
+				"builtInRoles":[{"builtInRole":"Admin"}]
+			}`))
+		case "/api/serviceaccounts/search":
+			_, _ = w.Write([]byte(`{"totalCount":0,"page":1,"perPage":50,"serviceAccounts":[]}`))
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	ctx := context.Background()
+
+	role := &v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: resourceTypeRole.Id, Resource: orgScopedID("1", "custom_reader")},
+		DisplayName: "Reader",
+	}
+
+	grants, _, _, err := newRoleBuilder(client, newServiceAccountIndex(client)).Grants(ctx, role, nil)
+	if err != nil {
+		t.Fatalf("Grants returned error: %v", err)
+	}
+
+	expected := []string{"user:2", "service_account:1/9", "team:7", "org:1"}
+	if len(grants) != len(expected) {
+		t.Fatalf("expected %d grants, got %d", len(expected), len(grants))
+	}
+	for i, g := range grants {
+		if g.Entitlement.Id != "role:1/custom_reader:assigned" {
+			t.Errorf("grant %d: entitlement %q", i, g.Entitlement.Id)
+		}
+		if principal := g.Principal.Id.ResourceType + ":" + g.Principal.Id.Resource; principal != expected[i] {
+			t.Errorf("grant %d: principal %q, expected %q", i, principal, expected[i])
+		}
+	}
+}
+
+func TestRoleList(t *testing.T) {
+	var fetched []string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path != "/api/access-control/roles" {
+			fetched = append(fetched, r.Header.Get("X-Grafana-Org-Id")+" "+r.URL.Path)
+		}
+		switch r.URL.Path {
+		case "/api/access-control/roles":
+			// The list endpoint leaves out the permissions of most roles.
+			_, _ = w.Write([]byte(`[
+				{"version":3,"uid":"custom_reader","name":"custom:reader","displayName":"Reader","description":"Reads dashboards","group":"Custom","global":false,"hidden":false,"updated":"2024-05-02T10:00:00Z","created":"2024-05-01T10:00:00Z"},
+				{"version":1,"uid":"fixed_dashboards_writer","name":"fixed:dashboards:writer","displayName":"Dashboard writer","group":"Dashboards","global":true,"hidden":false,"updated":"2024-01-01T00:00:00Z","created":"2024-01-01T00:00:00Z"},
+				{"version":2,"uid":"custom_listed","name":"custom:listed","group":"Custom","global":false,"permissions":[{"action":"folders:read","scope":"folders:*"}]},
+				{"version":1,"uid":"custom_deleted","name":"custom:deleted","group":"Custom","global":false}
+			]`))
+		case "/api/access-control/roles/custom_reader":
+			_, _ = w.Write([]byte(`{"version":3,"uid":"custom_reader","name":"custom:reader","displayName":"Reader","group":"Custom","global":false,
+				"permissions":[{"action":"dashboards:read","scope":"dashboards:*","updated":"2024-05-02T10:00:00Z","created":"2024-05-01T10:00:00Z"}]}`))
+		case "/api/access-control/roles/fixed_dashboards_writer":
+			_, _ = w.Write([]byte(`{"version":1,"uid":"fixed_dashboards_writer","name":"fixed:dashboards:writer","displayName":"Dashboard writer","group":"Dashboards","global":true,
+				"permissions":[{"action":"dashboards:read","scope":"dashboards:*"},{"action":"dashboards:write","scope":"dashboards:*"}]}`))
+		case "/api/access-control/roles/custom_deleted":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"role not found"}`))
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	builder := newRoleBuilder(client, nil)
+	list := func(orgID string) []*v2.Resource {
+		org := &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: orgID}
+		roles, _, _, err := builder.List(grafana.WithoutCache(context.Background()), org, &pagination.Token{})
+		if err != nil {
+			t.Fatalf("List returned error: %v", err)
+		}
+		return roles
+	}
+
+	roles := list("1")
+	list("1")
+	list("2")
+
+	// Global roles are fetched once for all organizations, and roles listed with permissions not at all.
+	// The deleted role is fetched again since it was not found.
+	expectedFetches := []string{
+		"1 /api/access-control/roles/custom_reader",
+		"1 /api/access-control/roles/fixed_dashboards_writer",
+		"1 /api/access-control/roles/custom_deleted",
+		"1 /api/access-control/roles/custom_deleted",
+		"2 /api/access-control/roles/custom_reader",
+		"2 /api/access-control/roles/custom_deleted",
+	}
+	if !slices.Equal(fetched, expectedFetches) {
+		t.Errorf("fetched roles %v, expected %v", fetched, expectedFetches)
+	}
+
+	expected := []struct {
+		id          string
+		permissions int
+	}{
+		{id: "1/custom_reader", permissions: 1},
+		{id: "1/fixed_dashboards_writer", permissions: 2},
+		{id: "1/custom_listed", permissions: 1},
+	}
+	if len(roles) != len(expected) {
+		t.Fatalf("expected %d roles, got %d", len(expected), len(roles))
+	}
+	for i, e := range expected {
+		if roles[i].Id.Resource != e.id {
+			t.Errorf("role %d: id %q, expected %q", i, roles[i].Id.Resource, e.id)
+		}
+
+		roleTrait := &v2.RoleTrait{}
+		annos := annotations.Annotations(roles[i].Annotations)
+		if ok, err := annos.Pick(roleTrait); err != nil || !ok {
+			t.Fatalf("role %d has no role trait", i)
+		}
+		if permissions := roleTrait.Profile.GetFields()["permissions"].GetListValue().GetValues(); len(permissions) != e.permissions {
+			t.Errorf("role %d: %d permissions, expected %d", i, len(permissions), e.permissions)
+		}
+	}
+}
+
+func TestRolesOnOSS(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	ctx := context.Background()
+
+	org := &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "1"}
+	roles, _, _, err := newRoleBuilder(client, nil).List(ctx, org, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("expected missing RBAC API to be skipped, got error: %v", err)
+	}
+	if len(roles) != 0 {
+		t.Errorf("expected no roles, got %d", len(roles))
+	}
+}
+
+func TestRoleProvisioning(t *testing.T) {
+	roleEntitlement := &v2.Entitlement{
+		Id:       "role:2/custom_alerting_editor:assigned",
+		Resource: &v2.Resource{Id: &v2.ResourceId{ResourceType: resourceTypeRole.Id, Resource: orgScopedID("2", "custom_alerting_editor")}},
+		Slug:     roleAssignedEntitlement,
+	}
+	testCases := []struct {
+		name      string
+		revoke    bool
+		principal *v2.Resource
+		expected  []string
+		unchanged bool
+		wantErr   bool
+	}{
+		{
+			name:      "grant user",
+			principal: testPrincipal(resourceTypeUser, "4"),
+			expected:  []string{`POST /api/access-control/users/4/roles {"global":false,"roleUid":"custom_alerting_editor"}`},
+		},
+		{
+			name:      "grant service account",
+			principal: testPrincipal(resourceTypeServiceAccount, "1/10"),
+			expected:  []string{`POST /api/access-control/users/10/roles {"global":false,"roleUid":"custom_alerting_editor"}`},
+		},
+		{
+			name:      "grant team",
+			principal: testPrincipal(resourceTypeTeam, "8"),
+			expected:  []string{`POST /api/access-control/teams/8/roles {"roleUid":"custom_alerting_editor"}`},
+		},
+		{name: "grant assigned user", principal: testPrincipal(resourceTypeUser, "2"), unchanged: true},
+		{name: "grant globally assigned user", principal: testPrincipal(resourceTypeUser, "3"), unchanged: true},
+		{
+			name:      "revoke user",
+			revoke:    true,
+			principal: testPrincipal(resourceTypeUser, "2"),
+			expected:  []string{`DELETE /api/access-control/users/2/roles/custom_alerting_editor`},
+		},
+		{
+			name:      "revoke service account",
+			revoke:    true,
+			principal: testPrincipal(resourceTypeServiceAccount, "1/9"),
+			expected:  []string{`DELETE /api/access-control/users/9/roles/custom_alerting_editor`},
+		},
+		{
+			name:      "revoke team",
+			revoke:    true,
+			principal: testPrincipal(resourceTypeTeam, "7"),
+			expected:  []string{`DELETE /api/access-control/teams/7/roles/custom_alerting_editor`},
+		},
+		{name: "revoke unassigned user", revoke: true, principal: testPrincipal(resourceTypeUser, "4"), unchanged: true},
+		{name: "revoke global assignment", revoke: true, principal: testPrincipal(resourceTypeUser, "3"), wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var recorder requestRecorder
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if org := r.Header.Get("X-Grafana-Org-Id"); org != "2" {
+					t.Errorf("request %s %s sent to org %q, expected 2", r.Method, r.URL.Path, org)
+				}
+				if r.Method == http.MethodGet && r.URL.Path == "/api/access-control/roles/custom_alerting_editor/assignments" {
+					_, _ = w.Write([]byte(`{
+						"roleUID":"custom_alerting_editor",
+						"users":[{"userId":2},{"userId":3,"global":true}],
+						"serviceAccounts":[{"serviceAccountId":9}],
+						"teams":[{"teamId":7}]
+					}`))
+					return
+				}
+				if r.Method == http.MethodDelete && r.URL.Query().Get("global") == "true" {
+					t.Errorf("unexpected global role removal %q", r.URL)
+				}
+				recorder.record(r)
+				_, _ = w.Write([]byte(`{"message":"Role updated"}`))
+			}))
+
+			unchanged, err := provision(newRoleBuilder(client, nil), tc.revoke, tc.principal, roleEntitlement)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				recorder.assertRequests(t, nil)
+				return
+			}
+			assertProvisioned(t, unchanged, err, tc.unchanged)
+			recorder.assertRequests(t, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type serviceAccountTokenBuilder struct {
+	resourceType *v2.ResourceType
+	client       *grafana.Client
+}
+
+// ResourceType returns the Baton resource type for service account tokens.
+func (s *serviceAccountTokenBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return resourceTypeServiceAccountToken
+}
+
+// withSecretProfile sets the profile of a secret trait.
+func withSecretProfile(profile map[string]interface{}) rs.SecretTraitOption {
+	return func(t *v2.SecretTrait) error {
+		p, err := structpb.NewStruct(profile)
+		if err != nil {
+			return err
+		}
+
+		t.Profile = p
+		return nil
+	}
+}
+
+// serviceAccountTokenResource creates a Baton secret resource for a token of a
+// Grafana service account. Tokens without an expiry are flagged with
+// "no_expiry" in the profile.
+func serviceAccountTokenResource(token *grafana.ServiceAccountToken, serviceAccountID string, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"token_id":           token.ID,
+		"service_account_id": serviceAccountID,
+		"has_expired":        token.HasExpired,
+		"is_revoked":         token.IsRevoked != nil && *token.IsRevoked,
+		"no_expiry":          token.Expiration == nil,
+	}
+
+	secretTraitOptions := []rs.SecretTraitOption{
+		withSecretProfile(profile),
+		rs.WithSecretIdentityID(parentResourceID),
+	}
+	if token.Created != nil {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretCreatedAt(*token.Created))
+	}
+	if token.LastUsedAt != nil {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretLastUsedAt(*token.LastUsedAt))
+	}
+	if token.Expiration != nil {
+		secretTraitOptions = append(secretTraitOptions, rs.WithSecretExpiresAt(*token.Expiration))
+	}
+
+	resource, err := rs.NewSecretResource(
+		token.Name,
+		resourceTypeServiceAccountToken,
+		token.ID,
+		secretTraitOptions,
+		rs.WithParentResourceID(parentResourceID),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns the tokens of the parent service account.
+func (s *serviceAccountTokenBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// Tokens are only listed as children of a service account.
+	if parentResourceID == nil || parentResourceID.ResourceType != resourceTypeServiceAccount.Id {
+		return nil, "", nil, nil
+	}
+
+	// The tokens endpoint is scoped to the organization of the service account.
+	orgID, serviceAccountID, err := parseOrgScopedID(parentResourceID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// Fetch tokens of the service account (The endpoint used in this method does not support pagination.)
+	tokens, annos, err := s.client.ListServiceAccountTokens(ctx, orgID, serviceAccountID)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list tokens of service account %s: %w", parentResourceID.Resource, err)
+	}
+
+	resources := make([]*v2.Resource, 0, len(tokens))
+	for _, token := range tokens {
+		resource, err := serviceAccountTokenResource(&token, serviceAccountID, parentResourceID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create resource for service account token %s: %w", token.Name, err)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, "", annos, nil
+}
+
+// Entitlements returns an empty list for service account tokens.
+func (s *serviceAccountTokenBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants returns an empty list for service account tokens.
+func (s *serviceAccountTokenBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// newServiceAccountTokenBuilder initializes a service account token resource type.
+func newServiceAccountTokenBuilder(client *grafana.Client) *serviceAccountTokenBuilder {
+	return &serviceAccountTokenBuilder{
+		resourceType: resourceTypeServiceAccountToken,
+		client:       client,
+	}
+}
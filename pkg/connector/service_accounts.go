@@ -0,0 +1,235 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+type serviceAccountBuilder struct {
+	resourceType *v2.ResourceType
+	client       *grafana.Client
+}
+
+// ResourceType returns the Baton resource type for service accounts.
+func (s *serviceAccountBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return resourceTypeServiceAccount
+}
+
+// serviceAccountResource creates a Baton resource for a Grafana service account under its organization.
+func serviceAccountResource(serviceAccount *grafana.ServiceAccount, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"service_account_id": serviceAccount.ID,
+		"login":              serviceAccount.Login,
+		"org_id":             serviceAccount.OrgID,
+		"role":               serviceAccount.Role,
+		"is_disabled":        serviceAccount.IsDisabled,
+		"tokens":             serviceAccount.Tokens,
+	}
+
+	status := v2.UserTrait_Status_STATUS_ENABLED
+	if serviceAccount.IsDisabled {
+		status = v2.UserTrait_Status_STATUS_DISABLED
+	}
+
+	userTraitOptions := []rs.UserTraitOption{
+		rs.WithUserProfile(profile),
+		rs.WithStatus(status),
+		rs.WithUserLogin(serviceAccount.Login),
+		rs.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_SERVICE),
+	}
+
+	resource, err := rs.NewUserResource(
+		serviceAccount.Name,
+		resourceTypeServiceAccount,
+		orgScopedID(parentResourceID.Resource, strconv.Itoa(serviceAccount.ID)),
+		userTraitOptions,
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: resourceTypeServiceAccountToken.Id}),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns the service accounts of the parent organization.
+func (s *serviceAccountBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// Service accounts are only listed as children of an organization.
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	// Parse pagination token. If Token is an empty string, the function returns 0.
+	bag, page, err := parsePageToken(pToken, &v2.ResourceId{ResourceType: resourceTypeServiceAccount.Id})
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to parse page token: %w", err)
+	}
+
+	paginationOpts := grafana.PaginationVars{
+		Size: ResourcesPageSize,
+		Page: page,
+	}
+
+	// Fetch service accounts of the organization from Grafana
+	serviceAccounts, numNextPage, annos, err := s.client.ListServiceAccounts(ctx, parentResourceID.Resource, &paginationOpts)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list service accounts under organization %s: %w", parentResourceID.Resource, err)
+	}
+
+	// Determine next page token
+	var pageToken string
+	if numNextPage > 0 {
+		pageToken = strconv.FormatUint(numNextPage, 10)
+	}
+
+	next, err := bag.NextToken(pageToken)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to generate next page token: %w", err)
+	}
+
+	resources := make([]*v2.Resource, 0, len(serviceAccounts))
+	for _, serviceAccount := range serviceAccounts {
+		resource, err := serviceAccountResource(&serviceAccount, parentResourceID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create resource for service account %s: %w", serviceAccount.Login, err)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, next, annos, nil
+}
+
+// Entitlements returns an empty list for service accounts.
+func (s *serviceAccountBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants returns an empty list for service accounts.
+func (s *serviceAccountBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// newServiceAccountBuilder initializes a service account resource type.
+func newServiceAccountBuilder(client *grafana.Client) *serviceAccountBuilder {
+	return &serviceAccountBuilder{
+		resourceType: resourceTypeServiceAccount,
+		client:       client,
+	}
+}
+
+// serviceAccountResourceID returns the resource ID of a service account. It is
+// scoped to the organization with orgScopedID, e.g. "1/9", since requests about
+// a service account, such as listing its tokens, are org-scoped.
+func serviceAccountResourceID(orgID string, serviceAccountID int) (*v2.ResourceId, error) {
+	return rs.NewResourceID(resourceTypeServiceAccount, orgScopedID(orgID, strconv.Itoa(serviceAccountID)))
+}
+
+// principalGrafanaID returns the Grafana ID of a user, service account or team
+// principal. Service accounts are users under the hood, so their ID is a user ID.
+func principalGrafanaID(principal *v2.ResourceId) (string, error) {
+	if principal.ResourceType != resourceTypeServiceAccount.Id {
+		return principal.Resource, nil
+	}
+
+	_, serviceAccountID, err := parseOrgScopedID(principal)
+	if err != nil {
+		return "", err
+	}
+
+	return serviceAccountID, nil
+}
+
+// serviceAccountIndex remembers the service account IDs of each organization.
+// Service accounts are users under the hood, so team member and permission
+// listings report them by user ID; the index tells them apart from human users.
+type serviceAccountIndex struct {
+	client *grafana.Client
+
+	mu  sync.Mutex
+	ids map[string]map[int]struct{}
+}
+
+func newServiceAccountIndex(client *grafana.Client) *serviceAccountIndex {
+	return &serviceAccountIndex{
+		client: client,
+		ids:    make(map[string]map[int]struct{}),
+	}
+}
+
+// reset forgets the loaded service accounts, so that each sync sees the service
+// accounts as they are at its start. A nil index has nothing to reset.
+func (s *serviceAccountIndex) reset() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ids = make(map[string]map[int]struct{})
+}
+
+// principalID returns the resource ID of the user or service account with the
+// given user ID in the organization. A nil index treats every ID as a user.
+func (s *serviceAccountIndex) principalID(ctx context.Context, orgID string, userID int) (*v2.ResourceId, error) {
+	if s != nil {
+		ids, err := s.load(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := ids[userID]; ok {
+			return serviceAccountResourceID(orgID, userID)
+		}
+	}
+
+	return rs.NewResourceID(resourceTypeUser, userID)
+}
+
+// load fetches the service account IDs of the organization on first use.
+func (s *serviceAccountIndex) load(ctx context.Context, orgID string) (map[int]struct{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ids, ok := s.ids[orgID]; ok {
+		return ids, nil
+	}
+
+	ids := make(map[int]struct{})
+	paginationOpts := grafana.PaginationVars{Size: ResourcesPageSize}
+	for {
+		// A response cached during the previous sync would defeat the reset.
+		serviceAccounts, nextPage, _, err := s.client.ListServiceAccounts(grafana.WithoutCache(ctx), orgID, &paginationOpts)
+		if err != nil {
+			// Instances without service accounts (before Grafana 9) don't have the endpoint.
+			if grafana.IsNotFound(err) {
+				break
+			}
+			return nil, fmt.Errorf("grafana-connector: failed to list service accounts under organization %s: %w", orgID, err)
+		}
+
+		for _, serviceAccount := range serviceAccounts {
+			ids[serviceAccount.ID] = struct{}{}
+		}
+
+		if nextPage == 0 {
+			break
+		}
+		paginationOpts.Page = nextPage
+	}
+
+	s.ids[orgID] = ids
+
+	return ids, nil
+}
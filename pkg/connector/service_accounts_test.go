@@ -0,0 +1,165 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func newServiceAccountTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/orgs/1/users/search":
+			_, _ = w.Write([]byte(`{"totalCount":3,"page":1,"perPage":50,"orgUsers":[{"userId":2,"login":"alice","role":"Admin"},{"userId":3,"login":"bob","role":"None"},{"userId":4,"login":"carol","role":"Auditor"}]}`))
+		case "/api/serviceaccounts/search":
+			_, _ = w.Write([]byte(`{"totalCount":1,"page":1,"perPage":50,"serviceAccounts":[{"id":9,"login":"sa-ci","orgId":1,"role":"Editor"}]}`))
+		case "/api/auth/keys":
+			_, _ = w.Write([]byte(`[{"id":4,"name":"legacy","role":"Admin","expiration":null}]`))
+		case "/api/orgs":
+			_, _ = w.Write([]byte(`[{"id":1,"name":"Main Org."}]`))
+		case "/api/serviceaccounts/9/tokens":
+			if got := r.Header.Get("X-Grafana-Org-Id"); got != "1" {
+				t.Errorf("expected X-Grafana-Org-Id 1, got %q", got)
+			}
+			_, _ = w.Write([]byte(`[
+				{"id":31,"name":"ci","created":"2024-01-02T03:04:05Z","lastUsedAt":"2024-06-01T00:00:00Z","expiration":null,"hasExpired":false,"isRevoked":false},
+				{"id":32,"name":"old","created":"2023-01-02T03:04:05Z","expiration":"2023-07-01T00:00:00Z","hasExpired":true}
+			]`))
+		case "/api/teams/7/members":
+			_, _ = w.Write([]byte(`[{"userId":2,"login":"alice","permission":0},{"userId":9,"login":"sa-ci","permission":4}]`))
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestTeamGrantsResolveServiceAccounts(t *testing.T) {
+	server := newServiceAccountTestServer(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := grafana.NewClient(ctx, server.URL, "admin", "admin", "")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	team := &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: resourceTypeTeam.Id, Resource: "7"},
+		ParentResourceId: &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "1"},
+		DisplayName:      "SRE",
+	}
+
+	grants, _, _, err := newTeamBuilder(client, newServiceAccountIndex(client)).Grants(ctx, team, nil)
+	if err != nil {
+		t.Fatalf("Grants returned error: %v", err)
+	}
+
+	expected := []string{"user:2 team:7:member", "service_account:1/9 team:7:member", "service_account:1/9 team:7:admin"}
+	if len(grants) != len(expected) {
+		t.Fatalf("expected %d grants, got %d", len(expected), len(grants))
+	}
+	for i, g := range grants {
+		if got := g.Principal.Id.ResourceType + ":" + g.Principal.Id.Resource + " " + g.Entitlement.Id; got != expected[i] {
+			t.Errorf("grant %d: %q, expected %q", i, got, expected[i])
+		}
+	}
+}
+
+func TestServiceAccountTokens(t *testing.T) {
+	server := newServiceAccountTestServer(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := grafana.NewClient(ctx, server.URL, "admin", "admin", "")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	serviceAccountID := &v2.ResourceId{ResourceType: resourceTypeServiceAccount.Id, Resource: "1/9"}
+	tokens, _, _, err := newServiceAccountTokenBuilder(client).List(ctx, serviceAccountID, &pagination.Token{})
+	if err != nil {
+		t.Fatalf("List returned error: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("expected 2 tokens, got %d", len(tokens))
+	}
+
+	secretTrait := func(r *v2.Resource) *v2.SecretTrait {
+		trait := &v2.SecretTrait{}
+		annos := annotations.Annotations(r.Annotations)
+		if ok, err := annos.Pick(trait); err != nil || !ok {
+			t.Fatalf("token %s has no secret trait", r.Id.Resource)
+		}
+		return trait
+	}
+
+	ci := secretTrait(tokens[0])
+	if !ci.Profile.GetFields()["no_expiry"].GetBoolValue() {
+		t.Error("expected token without expiration to be flagged with no_expiry")
+	}
+	if ci.ExpiresAt != nil || ci.LastUsedAt == nil || ci.IdentityId.GetResource() != "1/9" {
+		t.Errorf("unexpected secret trait for token without expiration: %v", ci)
+	}
+
+	old := secretTrait(tokens[1])
+	if old.Profile.GetFields()["no_expiry"].GetBoolValue() || !old.Profile.GetFields()["has_expired"].GetBoolValue() {
+		t.Errorf("unexpected profile for expired token: %v", old.Profile)
+	}
+	if old.ExpiresAt == nil {
+		t.Error("expected expired token to have an expiry")
+	}
+}
+
+func TestServiceAccountIndexResetPerSync(t *testing.T) {
+	serviceAccounts := `[]`
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/orgs":
+			_, _ = w.Write([]byte(`[{"id":1,"name":"Main Org."}]`))
+		case "/api/serviceaccounts/search":
+			_, _ = w.Write([]byte(`{"totalCount":1,"page":1,"perPage":50,"serviceAccounts":` + serviceAccounts + `}`))
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	ctx := context.Background()
+	index := newServiceAccountIndex(client)
+	g := &Grafana{client: client, serviceAccounts: index}
+
+	principalType := func() string {
+		id, err := index.principalID(ctx, "1", 9)
+		if err != nil {
+			t.Fatalf("principalID returned error: %v", err)
+		}
+		return id.ResourceType
+	}
+
+	if got := principalType(); got != resourceTypeUser.Id {
+		t.Fatalf("principal type %q before the service account exists, expected %q", got, resourceTypeUser.Id)
+	}
+
+	serviceAccounts = `[{"id":9,"login":"sa-ci","orgId":1,"role":"Editor"}]`
+	if got := principalType(); got != resourceTypeUser.Id {
+		t.Errorf("principal type %q within the same sync, expected %q", got, resourceTypeUser.Id)
+	}
+
+	if _, err := g.Validate(ctx); err != nil {
+		t.Fatalf("Validate returned error: %v", err)
+	}
+	if got := principalType(); got != resourceTypeServiceAccount.Id {
+		t.Errorf("principal type %q in the next sync, expected %q", got, resourceTypeServiceAccount.Id)
+	}
+}
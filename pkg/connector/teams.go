@@ -0,0 +1,296 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+const (
+	teamMemberEntitlement = "member"
+	teamAdminEntitlement  = "admin"
+)
+
+type teamBuilder struct {
+	resourceType    *v2.ResourceType
+	client          *grafana.Client
+	serviceAccounts *serviceAccountIndex
+}
+
+// ResourceType returns the Baton resource type for teams.
+func (t *teamBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return resourceTypeTeam
+}
+
+// teamResource creates a Baton resource for a Grafana team under its organization.
+func teamResource(team *grafana.Team, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"team_id":      team.ID,
+		"org_id":       team.OrgID,
+		"name":         team.Name,
+		"email":        team.Email,
+		"member_count": team.MemberCount,
+	}
+
+	resource, err := rs.NewGroupResource(
+		team.Name,
+		resourceTypeTeam,
+		team.ID,
+		[]rs.GroupTraitOption{rs.WithGroupProfile(profile)},
+		rs.WithParentResourceID(parentResourceID),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns the teams of the parent organization.
+func (t *teamBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// Teams are only listed as children of an organization.
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	// Parse pagination token. If Token is an empty string, the function returns 0.
+	bag, page, err := parsePageToken(pToken, &v2.ResourceId{ResourceType: resourceTypeTeam.Id})
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to parse page token: %w", err)
+	}
+
+	paginationOpts := grafana.PaginationVars{
+		Size: ResourcesPageSize,
+		Page: page,
+	}
+
+	// Fetch teams of the organization from Grafana
+	teams, numNextPage, annos, err := t.client.ListTeams(ctx, parentResourceID.Resource, &paginationOpts)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list teams under organization %s: %w", parentResourceID.Resource, err)
+	}
+
+	// Determine next page token
+	var pageToken string
+	if numNextPage > 0 {
+		pageToken = strconv.FormatUint(numNextPage, 10)
+	}
+
+	next, err := bag.NextToken(pageToken)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to generate next page token: %w", err)
+	}
+
+	resources := make([]*v2.Resource, 0, len(teams))
+	for _, team := range teams {
+		resource, err := teamResource(&team, parentResourceID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to create resource for team %s: %w", team.Name, err)
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources, next, annos, nil
+}
+
+// Entitlements returns the member and admin entitlements of a team.
+func (t *teamBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	entitlements := []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(
+			resource,
+			teamMemberEntitlement,
+			ent.WithGrantableTo(resourceTypeUser, resourceTypeServiceAccount),
+			ent.WithDisplayName(fmt.Sprintf("%s Team Member", resource.DisplayName)),
+			ent.WithDescription(fmt.Sprintf("Member of %s Grafana team", resource.DisplayName)),
+		),
+		ent.NewPermissionEntitlement(
+			resource,
+			teamAdminEntitlement,
+			ent.WithGrantableTo(resourceTypeUser, resourceTypeServiceAccount),
+			ent.WithDisplayName(fmt.Sprintf("%s Team Admin", resource.DisplayName)),
+			ent.WithDescription(fmt.Sprintf("Admin of %s Grafana team", resource.DisplayName)),
+		),
+	}
+
+	return entitlements, "", nil, nil
+}
+
+// Grants returns a member grant for every team member, and an admin grant for
+// members with the Admin team permission. Members may be users or service accounts.
+func (t *teamBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	if resource.ParentResourceId == nil {
+		return nil, "", nil, fmt.Errorf("grafana-connector: team %s has no parent organization", resource.Id.Resource)
+	}
+
+	// Fetch members of the team (The endpoint used in this method does not support pagination.)
+	members, annos, err := t.client.ListTeamMembers(ctx, resource.ParentResourceId.Resource, resource.Id.Resource)
+	if err != nil {
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list members of team %s: %w", resource.Id.Resource, err)
+	}
+
+	grants := make([]*v2.Grant, 0, len(members))
+	for _, member := range members {
+		principalID, err := t.serviceAccounts.principalID(ctx, resource.ParentResourceId.Resource, member.UserID)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to generate user resource id for %s: %w", member.Login, err)
+		}
+
+		grants = append(grants, grant.NewGrant(resource, teamMemberEntitlement, principalID))
+
+		if member.Permission == grafana.TeamPermissionAdmin {
+			grants = append(grants, grant.NewGrant(resource, teamAdminEntitlement, principalID))
+		}
+	}
+
+	return grants, "", annos, nil
+}
+
+// Grant adds a user or service account to the team, or makes a member a team
+// admin. Members and admins that already have the entitlement are left as they
+// are and the GrantAlreadyExists annotation is returned.
+func (t *teamBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
+	ctx = grafana.WithoutCache(ctx)
+
+	if principal.Id.ResourceType != resourceTypeUser.Id && principal.Id.ResourceType != resourceTypeServiceAccount.Id {
+		return nil, fmt.Errorf("grafana-connector: team entitlements can only be granted to users and service accounts, not %s", principal.Id.ResourceType)
+	}
+
+	team := entitlement.Resource
+	if team.ParentResourceId == nil {
+		return nil, fmt.Errorf("grafana-connector: team %s has no parent organization", team.Id.Resource)
+	}
+	orgID := team.ParentResourceId.Resource
+
+	principalID, err := principalGrafanaID(principal.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	member, annos, err := t.teamMember(ctx, orgID, team.Id.Resource, principalID)
+	if err != nil {
+		return annos, err
+	}
+
+	if member == nil {
+		userID, err := strconv.Atoi(principalID)
+		if err != nil {
+			return nil, fmt.Errorf("grafana-connector: invalid %s id %q: %w", principal.Id.ResourceType, principal.Id.Resource, err)
+		}
+
+		annos, err = t.client.AddTeamMember(ctx, orgID, team.Id.Resource, userID)
+		if err != nil {
+			return annos, fmt.Errorf("grafana-connector: failed to add %s %s to team %s: %w", principal.Id.ResourceType, principal.Id.Resource, team.Id.Resource, err)
+		}
+	}
+
+	switch entitlement.Slug {
+	case teamMemberEntitlement:
+		if member != nil {
+			annos.Update(&v2.GrantAlreadyExists{})
+		}
+		return annos, nil
+
+	case teamAdminEntitlement:
+		if member != nil && member.Permission == grafana.TeamPermissionAdmin {
+			annos.Update(&v2.GrantAlreadyExists{})
+			return annos, nil
+		}
+
+		annos, err = t.client.UpdateTeamMemberPermission(ctx, orgID, team.Id.Resource, principalID, grafana.TeamPermissionAdmin)
+		if err != nil {
+			return annos, fmt.Errorf("grafana-connector: failed to make %s %s admin of team %s: %w", principal.Id.ResourceType, principal.Id.Resource, team.Id.Resource, err)
+		}
+		return annos, nil
+
+	default:
+		return nil, fmt.Errorf("grafana-connector: unknown team entitlement %q", entitlement.Slug)
+	}
+}
+
+// Revoke removes a member from the team, or demotes a team admin to a plain
+// member. Revoking an entitlement the principal no longer has succeeds with the
+// GrantAlreadyRevoked annotation.
+func (t *teamBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	ctx = grafana.WithoutCache(ctx)
+
+	team := grant.Entitlement.Resource
+	principal := grant.Principal
+	if team.ParentResourceId == nil {
+		return nil, fmt.Errorf("grafana-connector: team %s has no parent organization", team.Id.Resource)
+	}
+	orgID := team.ParentResourceId.Resource
+
+	principalID, err := principalGrafanaID(principal.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	member, annos, err := t.teamMember(ctx, orgID, team.Id.Resource, principalID)
+	if err != nil {
+		return annos, err
+	}
+
+	switch grant.Entitlement.Slug {
+	case teamMemberEntitlement:
+		if member == nil {
+			annos.Update(&v2.GrantAlreadyRevoked{})
+			return annos, nil
+		}
+
+		annos, err = t.client.RemoveTeamMember(ctx, orgID, team.Id.Resource, principalID)
+		if err != nil {
+			return annos, fmt.Errorf("grafana-connector: failed to remove %s %s from team %s: %w", principal.Id.ResourceType, principal.Id.Resource, team.Id.Resource, err)
+		}
+		return annos, nil
+
+	case teamAdminEntitlement:
+		if member == nil || member.Permission != grafana.TeamPermissionAdmin {
+			annos.Update(&v2.GrantAlreadyRevoked{})
+			return annos, nil
+		}
+
+		annos, err = t.client.UpdateTeamMemberPermission(ctx, orgID, team.Id.Resource, principalID, grafana.TeamPermissionMember)
+		if err != nil {
+			return annos, fmt.Errorf("grafana-connector: failed to demote %s %s in team %s: %w", principal.Id.ResourceType, principal.Id.Resource, team.Id.Resource, err)
+		}
+		return annos, nil
+
+	default:
+		return nil, fmt.Errorf("grafana-connector: unknown team entitlement %q", grant.Entitlement.Slug)
+	}
+}
+
+// teamMember returns the team member with the given user ID, or nil if the user is not a member.
+func (t *teamBuilder) teamMember(ctx context.Context, orgID, teamID, userID string) (*grafana.TeamMember, annotations.Annotations, error) {
+	members, annos, err := t.client.ListTeamMembers(ctx, orgID, teamID)
+	if err != nil {
+		return nil, annos, fmt.Errorf("grafana-connector: failed to list members of team %s: %w", teamID, err)
+	}
+
+	for _, member := range members {
+		if strconv.Itoa(member.UserID) == userID {
+			return &member, annos, nil
+		}
+	}
+
+	return nil, annos, nil
+}
+
+// newTeamBuilder initializes a team resource type.
+func newTeamBuilder(client *grafana.Client, serviceAccounts *serviceAccountIndex) *teamBuilder {
+	return &teamBuilder{
+		resourceType:    resourceTypeTeam,
+		client:          client,
+		serviceAccounts: serviceAccounts,
+	}
+}
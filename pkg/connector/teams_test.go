@@ -0,0 +1,214 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+)
+
+func TestTeamList(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Grafana-Org-Id"); got != "1" {
+			t.Errorf("expected X-Grafana-Org-Id 1, got %q", got)
+		}
+
+		// Two teams per page, three teams in total.
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("page") {
+		case "1":
+			_, _ = w.Write([]byte(`{"totalCount":3,"page":1,"perPage":2,"teams":[{"id":7,"orgId":1,"name":"Ops","memberCount":2},{"id":8,"orgId":1,"name":"Dev","memberCount":5}]}`))
+		case "2":
+			_, _ = w.Write([]byte(`{"totalCount":3,"page":2,"perPage":2,"teams":[{"id":9,"orgId":1,"name":"QA","memberCount":0}]}`))
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	builder := newTeamBuilder(client, nil)
+	org := &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "1"}
+
+	var ids []string
+	token := &pagination.Token{}
+	for range 5 {
+		teams, next, _, err := builder.List(context.Background(), org, token)
+		if err != nil {
+			t.Fatalf("List returned error: %v", err)
+		}
+		for _, team := range teams {
+			if team.ParentResourceId.GetResource() != "1" {
+				t.Errorf("team %s has parent %v, expected org 1", team.Id.Resource, team.ParentResourceId)
+			}
+			ids = append(ids, team.Id.Resource)
+		}
+		if next == "" {
+			break
+		}
+		token = &pagination.Token{Token: next}
+	}
+
+	if !slices.Equal(ids, []string{"7", "8", "9"}) {
+		t.Errorf("listed teams %v, expected [7 8 9]", ids)
+	}
+}
+
+func TestTeamEntitlements(t *testing.T) {
+	team := teamEntitlement(teamMemberEntitlement).Resource
+
+	entitlements, _, _, err := newTeamBuilder(nil, nil).Entitlements(context.Background(), team, nil)
+	if err != nil {
+		t.Fatalf("Entitlements returned error: %v", err)
+	}
+
+	var ids []string
+	for _, e := range entitlements {
+		ids = append(ids, e.Id)
+	}
+	if !slices.Equal(ids, []string{"team:7:member", "team:7:admin"}) {
+		t.Errorf("entitlements %v, expected [team:7:member team:7:admin]", ids)
+	}
+}
+
+func TestTeamGrants(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode([]grafana.TeamMember{
+			{UserID: 2, Login: "alice", Permission: grafana.TeamPermissionMember},
+			{UserID: 3, Login: "bob", Permission: grafana.TeamPermissionAdmin},
+		})
+	}))
+
+	team := teamEntitlement(teamMemberEntitlement).Resource
+	grants, _, _, err := newTeamBuilder(client, nil).Grants(context.Background(), team, nil)
+	if err != nil {
+		t.Fatalf("Grants returned error: %v", err)
+	}
+
+	var got []string
+	for _, g := range grants {
+		got = append(got, g.Principal.Id.ResourceType+":"+g.Principal.Id.Resource+" "+g.Entitlement.Id)
+	}
+	expected := []string{"user:2 team:7:member", "user:3 team:7:member", "user:3 team:7:admin"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("grants %v, expected %v", got, expected)
+	}
+}
+
+// teamTestServer fakes the team member endpoints of team 7 in org 1 for a
+// single user (ID 2) and records the mutating requests it receives.
+type teamTestServer struct {
+	requestRecorder
+
+	member     bool
+	permission grafana.TeamPermission
+}
+
+func (s *teamTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var body map[string]int
+	if r.Method != http.MethodGet {
+		_ = json.Unmarshal(s.record(r), &body)
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/teams/7/members":
+		if !s.member {
+			_, _ = w.Write([]byte(`[]`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]grafana.TeamMember{{UserID: 2, Login: "alice", Permission: s.permission}})
+	case r.Method == http.MethodPost && r.URL.Path == "/api/teams/7/members":
+		s.member = true
+		s.permission = grafana.TeamPermissionMember
+		_, _ = w.Write([]byte(`{"message":"Member added to Team"}`))
+	case r.Method == http.MethodPut && r.URL.Path == "/api/teams/7/members/2":
+		s.permission = grafana.TeamPermission(body["permission"])
+		_, _ = w.Write([]byte(`{"message":"Team member updated"}`))
+	case r.Method == http.MethodDelete && r.URL.Path == "/api/teams/7/members/2":
+		s.member = false
+		_, _ = w.Write([]byte(`{"message":"Team member removed"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func teamEntitlement(slug string) *v2.Entitlement {
+	team := &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: resourceTypeTeam.Id, Resource: "7"},
+		ParentResourceId: &v2.ResourceId{ResourceType: resourceTypeOrg.Id, Resource: "1"},
+		DisplayName:      "Ops",
+	}
+	return &v2.Entitlement{Id: "team:7:" + slug, Resource: team, Slug: slug}
+}
+
+func TestTeamProvisioning(t *testing.T) {
+	testCases := []struct {
+		name        string
+		member      bool
+		permission  grafana.TeamPermission
+		revoke      bool
+		entitlement string
+		expected    []string
+		unchanged   bool
+	}{
+		{name: "add member", entitlement: teamMemberEntitlement, expected: []string{`POST /api/teams/7/members {"userId":2}`}},
+		{
+			name:        "add admin",
+			entitlement: teamAdminEntitlement,
+			expected:    []string{`POST /api/teams/7/members {"userId":2}`, `PUT /api/teams/7/members/2 {"permission":4}`},
+		},
+		{name: "promote member", member: true, entitlement: teamAdminEntitlement, expected: []string{`PUT /api/teams/7/members/2 {"permission":4}`}},
+		{name: "grant existing member", member: true, entitlement: teamMemberEntitlement, unchanged: true},
+		{name: "grant existing admin", member: true, permission: grafana.TeamPermissionAdmin, entitlement: teamAdminEntitlement, unchanged: true},
+		{name: "remove member", member: true, revoke: true, entitlement: teamMemberEntitlement, expected: []string{`DELETE /api/teams/7/members/2`}},
+		{
+			name:        "demote admin",
+			member:      true,
+			permission:  grafana.TeamPermissionAdmin,
+			revoke:      true,
+			entitlement: teamAdminEntitlement,
+			expected:    []string{`PUT /api/teams/7/members/2 {"permission":0}`},
+		},
+		{name: "revoke from non-member", revoke: true, entitlement: teamMemberEntitlement, unchanged: true},
+		{name: "revoke admin from member", member: true, revoke: true, entitlement: teamAdminEntitlement, unchanged: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &teamTestServer{member: tc.member, permission: tc.permission}
+			builder := newTeamBuilder(newTestClient(t, fake), nil)
+
+			unchanged, err := provision(builder, tc.revoke, orgTestUser, teamEntitlement(tc.entitlement))
+			assertProvisioned(t, unchanged, err, tc.unchanged)
+			fake.assertRequests(t, tc.expected)
+		})
+	}
+}
+
+func TestTeamProvisioningSequence(t *testing.T) {
+	fake := &teamTestServer{}
+	builder := newTeamBuilder(newTestClient(t, fake), nil)
+	entitlement := teamEntitlement(teamMemberEntitlement)
+
+	// A sync caches the member list before the team is provisioned.
+	if _, _, _, err := builder.Grants(context.Background(), entitlement.Resource, &pagination.Token{}); err != nil {
+		t.Fatalf("Grants returned error: %v", err)
+	}
+
+	for _, revoke := range []bool{false, true, false} {
+		unchanged, err := provision(builder, revoke, orgTestUser, entitlement)
+		assertProvisioned(t, unchanged, err, false)
+	}
+	fake.assertRequests(t, []string{
+		`POST /api/teams/7/members {"userId":2}`,
+		`DELETE /api/teams/7/members/2`,
+		`POST /api/teams/7/members {"userId":2}`,
+	})
+}
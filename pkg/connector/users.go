@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/conductorone/baton-grafana/pkg/grafana"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
 )
@@ -15,6 +17,7 @@ import (
 type userBuilder struct {
 	resourceType *v2.ResourceType
 	client       *grafana.Client
+	passwords    *passwordPolicy
 }
 
 // ResourceType returns the Baton resource type for users.
@@ -25,10 +28,11 @@ func (u *userBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 // userResource creates a Baton resource for a Grafana user.
 func userResource(user *grafana.User) (*v2.Resource, error) {
 	profile := map[string]interface{}{
-		"full_name": user.Name,
-		"login":     user.Login,
-		"user_id":   user.ID,
-		"email":     user.Email,
+		"full_name":       user.Name,
+		"login":           user.Login,
+		"user_id":         user.ID,
+		"email":           user.Email,
+		"is_server_admin": user.IsServerAdmin(),
 	}
 
 	status := v2.UserTrait_Status_STATUS_ENABLED
@@ -58,7 +62,8 @@ func userResource(user *grafana.User) (*v2.Resource, error) {
 
 // List fetches all users in Grafana.
 func (u *userBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	// Parse pagination token. If Token is an empty string, the function returns 0.
+	// Parse pagination token. If Token is an empty string, the function returns 0,
+	// which the client treats as the first page of the search.
 	bag, page, err := parsePageToken(pToken, &v2.ResourceId{ResourceType: resourceTypeUser.Id})
 	if err != nil {
 		return nil, "", nil, fmt.Errorf("failed to parse page token: %w", err)
@@ -70,9 +75,9 @@ func (u *userBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagina
 	}
 
 	// Fetch users from Grafana
-	users, numNextPage, err := u.client.ListUsers(ctx, &paginationOpts)
+	users, numNextPage, annos, err := u.client.ListUsers(ctx, &paginationOpts, "")
 	if err != nil {
-		return nil, "", nil, fmt.Errorf("grafana-connector: failed to list users: %w", err)
+		return nil, "", annos, fmt.Errorf("grafana-connector: failed to list users: %w", err)
 	}
 
 	// Generate next page token
@@ -97,7 +102,7 @@ func (u *userBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagina
 		resources = append(resources, ur)
 	}
 
-	return resources, next, nil, nil
+	return resources, next, annos, nil
 }
 
 // Entitlements returns an empty list for users.
@@ -110,10 +115,141 @@ func (u *userBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken
 	return nil, "", nil, nil
 }
 
+// CreateAccountCapabilityDetails reports that new users are created with a random password.
+func (u *userBuilder) CreateAccountCapabilityDetails(_ context.Context) (*v2.CredentialDetailsAccountProvisioning, annotations.Annotations, error) {
+	return &v2.CredentialDetailsAccountProvisioning{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
+	}, nil, nil
+}
+
+// CreateAccount creates a local Grafana user from the account info, with a
+// random password that is returned as plaintext data. The password follows the
+// connector's password policy, like rotated ones. The login defaults to the
+// primary email and the name to the login.
+func (u *userBuilder) CreateAccount(
+	ctx context.Context,
+	accountInfo *v2.AccountInfo,
+	credentialOptions *v2.CredentialOptions,
+) (connectorbuilder.CreateAccountResponse, []*v2.PlaintextData, annotations.Annotations, error) {
+	email := accountEmail(accountInfo)
+
+	login := accountInfo.GetLogin()
+	if login == "" {
+		login = email
+	}
+	if login == "" {
+		return nil, nil, nil, fmt.Errorf("grafana-connector: a login or email is required to create a user")
+	}
+
+	name := login
+	if profileName, ok := accountInfo.GetProfile().GetFields()["name"]; ok && profileName.GetStringValue() != "" {
+		name = profileName.GetStringValue()
+	}
+
+	password, err := u.passwords.generate(credentialOptions)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	userID, annos, err := u.client.CreateUser(ctx, login, email, name, password)
+	if err != nil {
+		return nil, nil, annos, fmt.Errorf("grafana-connector: failed to create user %s: %w", login, err)
+	}
+
+	// The user exists from here on, so it is described from the request rather
+	// than read back, which could fail after the creation has succeeded.
+	user := &grafana.User{ID: userID, Login: login, Email: email, Name: name}
+	resource, err := userResource(user)
+	if err != nil {
+		return nil, nil, annos, fmt.Errorf("failed to create resource for user %s: %w", login, err)
+	}
+
+	plaintexts := []*v2.PlaintextData{
+		{
+			Name:        "password",
+			Description: fmt.Sprintf("Password of the Grafana user %s", login),
+			Bytes:       []byte(password),
+		},
+	}
+
+	return &v2.CreateAccountResponse_SuccessResult{Resource: resource, IsCreateAccountResult: true}, plaintexts, annos, nil
+}
+
+// accountEmail returns the primary email of the account info, or its first email if none is primary.
+func accountEmail(accountInfo *v2.AccountInfo) string {
+	emails := accountInfo.GetEmails()
+	for _, email := range emails {
+		if email.GetIsPrimary() {
+			return email.GetAddress()
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].GetAddress()
+	}
+
+	return ""
+}
+
+// RotateCapabilityDetails reports that passwords are rotated to a random password.
+func (u *userBuilder) RotateCapabilityDetails(_ context.Context) (*v2.CredentialDetailsCredentialRotation, annotations.Annotations, error) {
+	return &v2.CredentialDetailsCredentialRotation{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_RANDOM_PASSWORD,
+	}, nil, nil
+}
+
+// Rotate sets a new random password for a local Grafana user and returns it as
+// plaintext data. Users synced from an external source such as LDAP, OAuth or
+// SAML are refused, since Grafana does not manage their passwords.
+func (u *userBuilder) Rotate(ctx context.Context, resourceID *v2.ResourceId, credentialOptions *v2.CredentialOptions) ([]*v2.PlaintextData, annotations.Annotations, error) {
+	if resourceID.ResourceType != resourceTypeUser.Id {
+		return nil, nil, fmt.Errorf("grafana-connector: passwords can only be rotated for users, not %s", resourceID.ResourceType)
+	}
+
+	user, annos, err := u.client.GetUser(ctx, resourceID.Resource)
+	if err != nil {
+		return nil, annos, fmt.Errorf("grafana-connector: failed to get user %s: %w", resourceID.Resource, err)
+	}
+
+	if user.IsExternal || user.IsExternallySynced {
+		source := "an external identity provider"
+		if len(user.AuthLabels) > 0 {
+			source = strings.Join(user.AuthLabels, ", ")
+		}
+		return nil, annos, fmt.Errorf("grafana-connector: refusing to rotate the password of %s, the user is synced from %s and its password is not managed by Grafana", user.Login, source)
+	}
+
+	password, err := u.passwords.generate(credentialOptions)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	annos, err = u.client.UpdateUserPassword(ctx, resourceID.Resource, password)
+	if err != nil {
+		return nil, annos, fmt.Errorf("grafana-connector: failed to rotate the password of %s: %w", user.Login, err)
+	}
+
+	plaintexts := []*v2.PlaintextData{
+		{
+			Name:        "password",
+			Description: fmt.Sprintf("Password of the Grafana user %s", user.Login),
+			Bytes:       []byte(password),
+		},
+	}
+
+	return plaintexts, annos, nil
+}
+
 // newUserBuilder initializes a user resource type.
-func newUserBuilder(client *grafana.Client) *userBuilder {
+func newUserBuilder(client *grafana.Client, passwords *passwordPolicy) *userBuilder {
 	return &userBuilder{
 		resourceType: resourceTypeUser,
 		client:       client,
+		passwords:    passwords,
 	}
 }
@@ -0,0 +1,206 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-grafana/pkg/grafana"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestCreateAccount(t *testing.T) {
+	var created map[string]string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/admin/users":
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			_, _ = w.Write([]byte(`{"id":5,"message":"User created"}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	ctx := context.Background()
+
+	profile, err := structpb.NewStruct(map[string]interface{}{"name": "Alice Doe"})
+	if err != nil {
+		t.Fatalf("NewStruct returned error: %v", err)
+	}
+	accountInfo := &v2.AccountInfo{
+		Login: "alice",
+		Emails: []*v2.AccountInfo_Email{
+			{Address: "alice@old.example.com"},
+			{Address: "alice@example.com", IsPrimary: true},
+		},
+		Profile: profile,
+	}
+	credentialOptions := &v2.CredentialOptions{
+		Options: &v2.CredentialOptions_RandomPassword_{RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 16}},
+	}
+
+	response, plaintexts, _, err := newUserBuilder(client, &passwordPolicy{classes: DefaultPasswordCharacterClasses}).CreateAccount(ctx, accountInfo, credentialOptions)
+	if err != nil {
+		t.Fatalf("CreateAccount returned error: %v", err)
+	}
+
+	if created["login"] != "alice" || created["email"] != "alice@example.com" || created["name"] != "Alice Doe" {
+		t.Errorf("unexpected user created: %v", created)
+	}
+	if len(plaintexts) != 1 || string(plaintexts[0].Bytes) != created["password"] || len(created["password"]) != 16 {
+		t.Errorf("expected the 16 character password sent to Grafana to be returned, got %v", plaintexts)
+	}
+
+	result, ok := response.(*v2.CreateAccountResponse_SuccessResult)
+	if !ok {
+		t.Fatalf("expected a success result, got %T", response)
+	}
+	if result.Resource.Id.Resource != "5" || result.Resource.DisplayName != "alice" {
+		t.Errorf("unexpected resource %v", result.Resource)
+	}
+
+	userTrait := &v2.UserTrait{}
+	annos := annotations.Annotations(result.Resource.Annotations)
+	if ok, err := annos.Pick(userTrait); err != nil || !ok {
+		t.Fatalf("created user has no user trait")
+	}
+	if len(userTrait.Emails) != 1 || userTrait.Emails[0].Address != "alice@example.com" {
+		t.Errorf("unexpected emails %v", userTrait.Emails)
+	}
+}
+
+func TestCreateAccountAppliesPasswordPolicy(t *testing.T) {
+	var created map[string]string
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewDecoder(r.Body).Decode(&created)
+		_, _ = w.Write([]byte(`{"id":5,"message":"User created"}`))
+	}))
+
+	credentialOptions := &v2.CredentialOptions{
+		Options: &v2.CredentialOptions_RandomPassword_{RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 12}},
+	}
+	builder := newUserBuilder(client, &passwordPolicy{classes: []string{characterClassLower, characterClassSymbol}})
+	if _, _, _, err := builder.CreateAccount(context.Background(), &v2.AccountInfo{Login: "alice"}, credentialOptions); err != nil {
+		t.Fatalf("CreateAccount returned error: %v", err)
+	}
+
+	password := created["password"]
+	lower, symbol := characterClassSets[characterClassLower], characterClassSets[characterClassSymbol]
+	if len(password) != 12 || strings.Trim(password, lower+symbol) != "" {
+		t.Errorf("password %q is not 12 lower case and symbol characters", password)
+	}
+	if !strings.ContainsAny(password, lower) || !strings.ContainsAny(password, symbol) {
+		t.Errorf("password %q is missing a character class", password)
+	}
+}
+
+func TestCreateAccountRequiresRandomPassword(t *testing.T) {
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	}))
+
+	credentialOptions := &v2.CredentialOptions{Options: &v2.CredentialOptions_NoPassword_{NoPassword: &v2.CredentialOptions_NoPassword{}}}
+	_, _, _, err := newUserBuilder(client, &passwordPolicy{classes: DefaultPasswordCharacterClasses}).CreateAccount(context.Background(), &v2.AccountInfo{Login: "alice"}, credentialOptions)
+	if err == nil {
+		t.Fatal("expected an error without a random password option")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		user    grafana.User
+		wantErr bool
+	}{
+		{name: "local user", user: grafana.User{ID: 2, Login: "breakglass"}},
+		{name: "LDAP user", user: grafana.User{ID: 2, Login: "alice", IsExternal: true, AuthLabels: []string{"LDAP"}}, wantErr: true},
+		{name: "synced OAuth user", user: grafana.User{ID: 2, Login: "bob", IsExternallySynced: true, AuthLabels: []string{"OAuth"}}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var updated map[string]string
+			client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/api/users/2":
+					_ = json.NewEncoder(w).Encode(tc.user)
+				case r.Method == http.MethodPut && r.URL.Path == "/api/admin/users/2/password":
+					_ = json.NewDecoder(r.Body).Decode(&updated)
+					_, _ = w.Write([]byte(`{"message":"User password updated"}`))
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+
+			ctx := context.Background()
+
+			credentialOptions := &v2.CredentialOptions{
+				Options: &v2.CredentialOptions_RandomPassword_{RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 20}},
+			}
+			builder := newUserBuilder(client, &passwordPolicy{classes: []string{characterClassLower, characterClassDigit}})
+			plaintexts, _, err := builder.Rotate(ctx, &v2.ResourceId{ResourceType: resourceTypeUser.Id, Resource: "2"}, credentialOptions)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				if updated != nil {
+					t.Errorf("expected the password to be left unchanged, got %v", updated)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Rotate returned error: %v", err)
+			}
+
+			if len(plaintexts) != 1 || string(plaintexts[0].Bytes) != updated["password"] || len(updated["password"]) != 20 {
+				t.Errorf("expected the 20 character password sent to Grafana to be returned, got %v", plaintexts)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	policy, err := newPasswordPolicy([]string{"Upper", " digit", "upper"})
+	if err != nil {
+		t.Fatalf("newPasswordPolicy returned error: %v", err)
+	}
+
+	credentialOptions := &v2.CredentialOptions{
+		Options: &v2.CredentialOptions_RandomPassword_{RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 12}},
+	}
+	for range 20 {
+		password, err := policy.generate(credentialOptions)
+		if err != nil {
+			t.Fatalf("generate returned error: %v", err)
+		}
+		if len(password) != 12 {
+			t.Errorf("password %q has length %d, expected 12", password, len(password))
+		}
+		if strings.Trim(password, characterClassSets[characterClassUpper]+characterClassSets[characterClassDigit]) != "" {
+			t.Errorf("password %q has characters outside the upper and digit classes", password)
+		}
+		if !strings.ContainsAny(password, characterClassSets[characterClassUpper]) || !strings.ContainsAny(password, characterClassSets[characterClassDigit]) {
+			t.Errorf("password %q is missing a character class", password)
+		}
+	}
+
+	short := &v2.CredentialOptions{
+		Options: &v2.CredentialOptions_RandomPassword_{RandomPassword: &v2.CredentialOptions_RandomPassword{Length: 6}},
+	}
+	if _, err := policy.generate(short); err == nil {
+		t.Error("expected an error for a password shorter than 8 characters")
+	}
+
+	if _, err := newPasswordPolicy([]string{"emoji"}); err == nil {
+		t.Error("expected an error for an unknown character class")
+	}
+}
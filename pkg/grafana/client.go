@@ -1,25 +1,70 @@
 package grafana
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/ratelimit"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 )
 
 const (
-	ListUsersPath      = "/api/users"
-	ListOrgsPath       = "/api/orgs"
-	ListUsersInOrgPath = "/api/orgs/%s/users"
+	SearchUsersPath              = "/api/users/search"
+	ListOrgsPath                 = "/api/orgs"
+	CurrentOrgPath               = "/api/org"
+	CurrentOrgUsersPath          = "/api/org/users"
+	CurrentOrgUserPath           = "/api/org/users/%s"
+	SearchCurrentOrgUsersPath    = "/api/org/users/search"
+	SearchUsersInOrgPath         = "/api/orgs/%s/users/search"
+	SearchTeamsPath              = "/api/teams/search"
+	ListTeamMembersPath          = "/api/teams/%s/members"
+	ListFoldersPath              = "/api/folders"
+	FolderPermissionPath         = "/api/folders/%s/permissions"
+	SearchDashboardsPath         = "/api/search?type=dash-db"
+	DashboardPermissionPath      = "/api/dashboards/uid/%s/permissions"
+	ListDataSourcesPath          = "/api/datasources"
+	DataSourcePermissionPath     = "/api/access-control/datasources/%s"
+	SearchServiceAccountsPath    = "/api/serviceaccounts/search"
+	ListServiceAccountTokensPath = "/api/serviceaccounts/%s/tokens"
+	ListAPIKeysPath              = "/api/auth/keys?includeExpired=true"
+	APIKeyMigrationStatusPath    = "/api/serviceaccounts/migrationstatus"
+	ListRolesPath                = "/api/access-control/roles"
+	RolePath                     = "/api/access-control/roles/%s"
+	RoleAssignmentsPath          = "/api/access-control/roles/%s/assignments"
+	UserPath                     = "/api/users/%s"
+	ListUserOrgsPath             = "/api/users/%s/orgs"
+	OrgUsersPath                 = "/api/orgs/%s/users"
+	OrgUserPath                  = "/api/orgs/%s/users/%s"
+	ServiceAccountPath           = "/api/serviceaccounts/%s"
+	TeamMemberPath               = "/api/teams/%s/members/%s"
+	UserPermissionPath           = "/api/access-control/%s/%s/users/%s"
+	TeamPermissionPath           = "/api/access-control/%s/%s/teams/%s"
+	UserRolesPath                = "/api/access-control/users/%s/roles"
+	UserRolePath                 = "/api/access-control/users/%s/roles/%s?global=false"
+	TeamRolesPath                = "/api/access-control/teams/%s/roles"
+	TeamRolePath                 = "/api/access-control/teams/%s/roles/%s"
+	SignedInUserPath             = "/api/user"
+	UserServerAdminPath          = "/api/admin/users/%s/permissions"
+	AdminUsersPath               = "/api/admin/users"
+	UserPasswordPath             = "/api/admin/users/%s/password"
 )
 
 // NewClient initializes a new Grafana API client.
-func NewClient(ctx context.Context, hostname, username, password string) (*Client, error) {
-	baseUrl, err := url.Parse(hostname)
+// If apiToken is set, requests are authenticated with a service account token
+// (Bearer auth); otherwise HTTP Basic auth is built from username and password.
+func NewClient(ctx context.Context, hostname, username, password, apiToken string, opts ...ClientOption) (*Client, error) {
+	baseUrl, err := parseBaseURL(hostname)
 	if err != nil {
 		return nil, err
 	}
@@ -34,43 +79,113 @@ func NewClient(ctx context.Context, hostname, username, password string) (*Clien
 		return nil, err
 	}
 
-	return &Client{
-		httpClient: wrapper,
-		baseUrl:    baseUrl,
-		username:   username,
-		password:   password,
-	}, nil
+	client := &Client{
+		httpClient:  wrapper,
+		baseUrl:     baseUrl,
+		username:    username,
+		password:    password,
+		apiToken:    apiToken,
+		retryPolicy: DefaultRetryPolicy(),
+	}
+
+	for _, opt := range opts {
+		opt(client)
+	}
+
+	return client, nil
+}
+
+// parseBaseURL parses the configured Grafana hostname and normalises its path,
+// so that "https://example.com/grafana" and "https://example.com/grafana/" are
+// treated the same.
+func parseBaseURL(hostname string) (*url.URL, error) {
+	baseUrl, err := url.Parse(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	if baseUrl.Scheme == "" || baseUrl.Host == "" {
+		return nil, fmt.Errorf("grafana-connector: invalid hostname %q: scheme and host are required", hostname)
+	}
+
+	baseUrl.Path = strings.TrimRight(baseUrl.Path, "/")
+	baseUrl.RawPath = strings.TrimRight(baseUrl.RawPath, "/")
+
+	return baseUrl, nil
 }
 
 // buildResourceURL constructs an absolute URL by formatting a resource path
-// template (like "/api/orgs/%d/users") with optional parameters, then resolving it
-// against c.baseURL.
+// template (like "/api/orgs/%d/users") with optional parameters, then joining it
+// with the path of c.baseURL.
 //
 // Example:
 //
-//	If c.baseURL is https://example.com/ and you call:
+//	If c.baseURL is https://example.com/grafana/ and you call:
 //	    buildResourceURL("/api/orgs/%d/users", 42)
-//	The final URL might be:
-//	    https://example.com/api/orgs/42/users
+//	The final URL will be:
+//	    https://example.com/grafana/api/orgs/42/users
 //
-// If no parameters are given, the template is used as-is.
-// Any errors (like invalid baseURL) can be handled as needed.
+// If no parameters are given, the template is used as-is. A query string in the
+// template (like "/api/search?type=dash-folder") is preserved and appended to
+// any query already present on c.baseURL.
 func (c *Client) buildResourceURL(pathTemplate string, args ...interface{}) *url.URL {
 	// If no parameters, just use the raw template
 	finalPath := pathTemplate
 	if len(args) > 0 {
 		finalPath = fmt.Sprintf(pathTemplate, args...)
 	}
-	// ResolveReference merges the base URL and finalPath into an absolute URL.
-	return c.baseUrl.ResolveReference(&url.URL{Path: finalPath})
+
+	resourcePath, rawQuery, _ := strings.Cut(finalPath, "?")
+
+	// JoinPath keeps the base path (e.g. a reverse proxy sub-path such as
+	// "/grafana") instead of replacing it, as ResolveReference would for an
+	// absolute path.
+	resourceURL := c.baseUrl.JoinPath(resourcePath)
+	if !strings.HasPrefix(resourceURL.Path, "/") {
+		// JoinPath keeps an empty base path relative; API paths are always absolute.
+		resourceURL.Path = "/" + resourceURL.Path
+		resourceURL.RawPath = ""
+	}
+	resourceURL.Fragment = ""
+	resourceURL.RawFragment = ""
+
+	switch {
+	case rawQuery == "":
+	case resourceURL.RawQuery == "":
+		resourceURL.RawQuery = rawQuery
+	default:
+		resourceURL.RawQuery = resourceURL.RawQuery + "&" + rawQuery
+	}
+
+	return resourceURL
 }
 
-// ListOrganizations return organizations for the current user.
-func (c *Client) ListOrganizations(ctx context.Context, pVars *PaginationVars) ([]Organization, uint64, error) {
+// UsesServiceAccountToken reports whether the client authenticates with a
+// service account token rather than a username and password.
+func (c *Client) UsesServiceAccountToken() bool {
+	return c.apiToken != ""
+}
+
+// ListOrganizations return organizations for the current user. Listing every
+// organization requires a Grafana server admin, which service accounts cannot
+// be, so with a service account token only the organization of the service
+// account is returned.
+func (c *Client) ListOrganizations(ctx context.Context, pVars *PaginationVars) ([]Organization, uint64, annotations.Annotations, error) {
+	if c.UsesServiceAccountToken() {
+		var organizationResponse Organization
+
+		annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(CurrentOrgPath), &organizationResponse, nil, nil)
+		if err != nil {
+			return nil, 0, annos, err
+		}
+
+		return []Organization{organizationResponse}, 0, annos, nil
+	}
+
 	var organizationsResponse []Organization
 	var nextPage uint64
 
-	err := c.doRequest(
+	annos, err := c.doRequest(
 		ctx,
 		http.MethodGet,
 		c.buildResourceURL(ListOrgsPath),
@@ -79,7 +194,7 @@ func (c *Client) ListOrganizations(ctx context.Context, pVars *PaginationVars) (
 		pVars,
 	)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, annos, err
 	}
 
 	// Grafana does not provide "nextPage", so we check if we got fewer results than requested
@@ -87,38 +202,597 @@ func (c *Client) ListOrganizations(ctx context.Context, pVars *PaginationVars) (
 		nextPage = pVars.Page + 1
 	}
 
-	return organizationsResponse, nextPage, nil
+	return organizationsResponse, nextPage, annos, nil
+}
+
+// ListUsersByOrg fetches a page of users in a given Grafana organization using
+// the paginated org users search endpoint. Pages are 1-based; a page of 0 is
+// treated as the first page. The returned next page is 0 when there are no more
+// pages. With a service account token the organization of the service account
+// is searched, since the endpoint of other organizations requires a server admin.
+func (c *Client) ListUsersByOrg(ctx context.Context, orgID string, pVars *PaginationVars) ([]UserByOrgResponse, uint64, annotations.Annotations, error) {
+	var usersByOrgResponse OrgUserSearchResponse
+
+	page := max(pVars.Page, 1)
+	searchVars := &PaginationVars{Size: pVars.Size, Page: page}
+
+	searchURL := c.buildResourceURL(SearchUsersInOrgPath, orgID)
+	if c.UsesServiceAccountToken() {
+		searchURL = c.buildResourceURL(SearchCurrentOrgUsersPath)
+	}
+
+	annos, err := c.doRequest(ctx, http.MethodGet, searchURL, &usersByOrgResponse, nil, searchVars)
+	if err != nil {
+		return nil, 0, annos, err
+	}
+
+	nextPage := nextSearchPage(page, usersByOrgResponse.PerPage, pVars.Size, usersByOrgResponse.TotalCount)
+
+	return usersByOrgResponse.OrgUsers, nextPage, annos, nil
+}
+
+// ListUsers fetches a page of users in Grafana using the paginated search
+// endpoint. Pages are 1-based; a page of 0 is treated as the first page. If
+// query is not empty, only users whose login, email or name match it are
+// returned. The returned next page is 0 when there are no more pages.
+//
+// Searching all users requires a Grafana server admin, so with a service
+// account token the members of the service account's organization are
+// searched instead. Their server admin flag is not reported.
+func (c *Client) ListUsers(ctx context.Context, pVars *PaginationVars, query string) ([]User, uint64, annotations.Annotations, error) {
+	page := max(pVars.Page, 1)
+	searchVars := &PaginationVars{Size: pVars.Size, Page: page}
+
+	if c.UsesServiceAccountToken() {
+		var orgUsersResponse OrgUserSearchResponse
+
+		annos, err := c.doRequest(ctx, http.MethodGet, c.userSearchURL(SearchCurrentOrgUsersPath, query), &orgUsersResponse, nil, searchVars)
+		if err != nil {
+			return nil, 0, annos, err
+		}
+
+		users := make([]User, 0, len(orgUsersResponse.OrgUsers))
+		for _, orgUser := range orgUsersResponse.OrgUsers {
+			users = append(users, orgUser.ToUser())
+		}
+
+		return users, nextSearchPage(page, orgUsersResponse.PerPage, pVars.Size, orgUsersResponse.TotalCount), annos, nil
+	}
+
+	var usersResponse UserSearchResponse
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.userSearchURL(SearchUsersPath, query), &usersResponse, nil, searchVars)
+	if err != nil {
+		return nil, 0, annos, err
+	}
+
+	nextPage := nextSearchPage(page, usersResponse.PerPage, pVars.Size, usersResponse.TotalCount)
+
+	return usersResponse.Users, nextPage, annos, nil
+}
+
+// userSearchURL returns the URL of a user search endpoint, filtered by query if it is not empty.
+func (c *Client) userSearchURL(path, query string) *url.URL {
+	searchURL := c.buildResourceURL(path)
+	if query != "" {
+		q := searchURL.Query()
+		q.Set("query", query)
+		searchURL.RawQuery = q.Encode()
+	}
+
+	return searchURL
+}
+
+// nextSearchPage computes the page following page for Grafana search
+// endpoints that report a total count. The page size reported by Grafana takes
+// precedence over the requested one, since Grafana may cap "perpage". It returns
+// 0 when page is the last page.
+func nextSearchPage(page uint64, perPage int, requestedSize uint64, totalCount int) uint64 {
+	size := requestedSize
+	if perPage > 0 {
+		size = uint64(perPage)
+	}
+
+	if size == 0 || totalCount <= 0 {
+		return 0
+	}
+
+	lastPage := (uint64(totalCount) + size - 1) / size
+	if page >= lastPage {
+		return 0
+	}
+
+	return page + 1
 }
 
-// ListUsersByOrg fetches all users in a given Grafana organization.
-func (c *Client) ListUsersByOrg(ctx context.Context, orgID string) ([]UserByOrgResponse, error) {
-	var usersByOrgResponse []UserByOrgResponse
+// ListTeams fetches a page of teams in the given organization. Pages are
+// 1-based; a page of 0 is treated as the first page. The returned next page is
+// 0 when there are no more pages.
+func (c *Client) ListTeams(ctx context.Context, orgID string, pVars *PaginationVars) ([]Team, uint64, annotations.Annotations, error) {
+	var teamsResponse TeamSearchResponse
+
+	page := max(pVars.Page, 1)
+	searchVars := &PaginationVars{Size: pVars.Size, Page: page}
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(SearchTeamsPath), &teamsResponse, nil, searchVars, withOrgID(orgID))
+	if err != nil {
+		return nil, 0, annos, err
+	}
+
+	nextPage := nextSearchPage(page, teamsResponse.PerPage, pVars.Size, teamsResponse.TotalCount)
+
+	return teamsResponse.Teams, nextPage, annos, nil
+}
+
+// ListTeamMembers fetches all members of a team in the given organization.
+func (c *Client) ListTeamMembers(ctx context.Context, orgID, teamID string) ([]TeamMember, annotations.Annotations, error) {
+	var membersResponse []TeamMember
 
 	// Make the request without pagination as the endpoint does not support it
-	err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(ListUsersInOrgPath, orgID), &usersByOrgResponse, nil, nil)
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(ListTeamMembersPath, teamID), &membersResponse, nil, nil, withOrgID(orgID))
 	if err != nil {
-		return nil, err
+		return nil, annos, err
 	}
 
-	return usersByOrgResponse, nil
+	return membersResponse, annos, nil
 }
 
-// ListUsers fetches all users in Grafana.
-func (c *Client) ListUsers(ctx context.Context, pVars *PaginationVars) ([]User, uint64, error) {
-	var usersResponse []User
+// ListFolders fetches a page of folders in the given organization. If
+// parentUID is empty, the root-level folders are returned; otherwise the direct
+// children of that folder (Grafana 10+ nested folders). The endpoint does not
+// report a total count, so a next page is assumed whenever a full page is
+// returned. Pages are 1-based; a page of 0 is treated as the first page.
+func (c *Client) ListFolders(ctx context.Context, orgID, parentUID string, pVars *PaginationVars) ([]Folder, uint64, annotations.Annotations, error) {
+	var foldersResponse []Folder
 	var nextPage uint64
 
-	err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(ListUsersPath), &usersResponse, nil, pVars)
+	page := max(pVars.Page, 1)
+
+	// The folders endpoint uses "limit" instead of "perpage".
+	foldersURL := c.buildResourceURL(ListFoldersPath)
+	q := foldersURL.Query()
+	q.Set("limit", strconv.FormatUint(pVars.Size, 10))
+	q.Set("page", strconv.FormatUint(page, 10))
+	if parentUID != "" {
+		q.Set("parentUid", parentUID)
+	}
+	foldersURL.RawQuery = q.Encode()
+
+	annos, err := c.doRequest(ctx, http.MethodGet, foldersURL, &foldersResponse, nil, nil, withOrgID(orgID))
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, annos, err
 	}
 
-	// Grafana does not provide "nextPage", so we check if we got fewer results than requested
-	if uint64(len(usersResponse)) == pVars.Size {
-		nextPage = pVars.Page + 1
+	if uint64(len(foldersResponse)) == pVars.Size {
+		nextPage = page + 1
+	}
+
+	return foldersResponse, nextPage, annos, nil
+}
+
+// ListFolderPermissions fetches the permission entries of a folder.
+func (c *Client) ListFolderPermissions(ctx context.Context, orgID, folderUID string) ([]ResourcePermission, annotations.Annotations, error) {
+	var permissionsResponse []ResourcePermission
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(FolderPermissionPath, folderUID), &permissionsResponse, nil, nil, withOrgID(orgID))
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return permissionsResponse, annos, nil
+}
+
+// ListDashboards fetches a page of dashboards in the given organization. If
+// folderUID is empty, the dashboards at the root level (the "General" folder)
+// are returned; otherwise those directly in that folder. The search endpoint
+// does not report a total count, so a next page is assumed whenever a full page
+// is returned. Pages are 1-based; a page of 0 is treated as the first page.
+func (c *Client) ListDashboards(ctx context.Context, orgID, folderUID string, pVars *PaginationVars) ([]Dashboard, uint64, annotations.Annotations, error) {
+	var dashboardsResponse []Dashboard
+	var nextPage uint64
+
+	page := max(pVars.Page, 1)
+
+	// The search endpoint uses "limit" instead of "perpage".
+	searchURL := c.buildResourceURL(SearchDashboardsPath)
+	q := searchURL.Query()
+	q.Set("limit", strconv.FormatUint(pVars.Size, 10))
+	q.Set("page", strconv.FormatUint(page, 10))
+	if folderUID != "" {
+		q.Set("folderUIDs", folderUID)
+	} else {
+		// Folder ID 0 is the "General" folder holding root-level dashboards.
+		q.Set("folderIds", "0")
+	}
+	searchURL.RawQuery = q.Encode()
+
+	annos, err := c.doRequest(ctx, http.MethodGet, searchURL, &dashboardsResponse, nil, nil, withOrgID(orgID))
+	if err != nil {
+		return nil, 0, annos, err
+	}
+
+	if uint64(len(dashboardsResponse)) == pVars.Size {
+		nextPage = page + 1
+	}
+
+	return dashboardsResponse, nextPage, annos, nil
+}
+
+// ListDashboardPermissions fetches the permission entries of a dashboard,
+// including those inherited from its folder.
+func (c *Client) ListDashboardPermissions(ctx context.Context, orgID, dashboardUID string) ([]ResourcePermission, annotations.Annotations, error) {
+	var permissionsResponse []ResourcePermission
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(DashboardPermissionPath, dashboardUID), &permissionsResponse, nil, nil, withOrgID(orgID))
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return permissionsResponse, annos, nil
+}
+
+// ListDataSources returns the data sources of the given organization.
+// The endpoint does not support pagination.
+func (c *Client) ListDataSources(ctx context.Context, orgID string) ([]DataSource, annotations.Annotations, error) {
+	var dataSourcesResponse []DataSource
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(ListDataSourcesPath), &dataSourcesResponse, nil, nil, withOrgID(orgID))
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return dataSourcesResponse, annos, nil
+}
+
+// ListDataSourcePermissions returns the permissions of a data source. The
+// endpoint is only available on Grafana Enterprise and Cloud; OSS editions
+// respond with 404, which callers can detect with IsNotFound.
+func (c *Client) ListDataSourcePermissions(ctx context.Context, orgID, dataSourceUID string) ([]DataSourcePermission, annotations.Annotations, error) {
+	var permissionsResponse []DataSourcePermission
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(DataSourcePermissionPath, dataSourceUID), &permissionsResponse, nil, nil, withOrgID(orgID))
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return permissionsResponse, annos, nil
+}
+
+// ListServiceAccounts returns a page of the service accounts of the given organization.
+// Pages are 1-based; a page of 0 is treated as the first page.
+func (c *Client) ListServiceAccounts(ctx context.Context, orgID string, pVars *PaginationVars) ([]ServiceAccount, uint64, annotations.Annotations, error) {
+	var serviceAccountsResponse ServiceAccountSearchResponse
+
+	page := max(pVars.Page, 1)
+	searchVars := &PaginationVars{Size: pVars.Size, Page: page}
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(SearchServiceAccountsPath), &serviceAccountsResponse, nil, searchVars, withOrgID(orgID))
+	if err != nil {
+		return nil, 0, annos, err
+	}
+
+	nextPage := nextSearchPage(page, serviceAccountsResponse.PerPage, pVars.Size, serviceAccountsResponse.TotalCount)
+
+	return serviceAccountsResponse.ServiceAccounts, nextPage, annos, nil
+}
+
+// ListServiceAccountTokens returns the tokens of a service account in the given organization.
+// The endpoint does not support pagination.
+func (c *Client) ListServiceAccountTokens(ctx context.Context, orgID, serviceAccountID string) ([]ServiceAccountToken, annotations.Annotations, error) {
+	var tokensResponse []ServiceAccountToken
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(ListServiceAccountTokensPath, serviceAccountID), &tokensResponse, nil, nil, withOrgID(orgID))
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return tokensResponse, annos, nil
+}
+
+// ListAPIKeys returns the legacy API keys of the given organization, including
+// expired ones. Grafana versions that removed API keys respond with 404 or 410,
+// which callers can detect with IsNotFound and IsGone.
+func (c *Client) ListAPIKeys(ctx context.Context, orgID string) ([]APIKey, annotations.Annotations, error) {
+	var keysResponse []APIKey
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(ListAPIKeysPath), &keysResponse, nil, nil, withOrgID(orgID))
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return keysResponse, annos, nil
+}
+
+// GetAPIKeyMigrationStatus returns whether the legacy API keys of the given
+// organization have been migrated to service accounts. Grafana versions without
+// the migration (before 9.1, or after API keys were removed) respond with 404.
+func (c *Client) GetAPIKeyMigrationStatus(ctx context.Context, orgID string) (*APIKeyMigrationStatus, annotations.Annotations, error) {
+	var statusResponse APIKeyMigrationStatus
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(APIKeyMigrationStatusPath), &statusResponse, nil, nil, withOrgID(orgID))
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return &statusResponse, annos, nil
+}
+
+// ListRoles returns the RBAC roles visible in the given organization, without
+// their permissions. The endpoint is only available on Grafana Enterprise and
+// Cloud; OSS editions respond with 404. The endpoint does not support pagination.
+func (c *Client) ListRoles(ctx context.Context, orgID string) ([]Role, annotations.Annotations, error) {
+	var rolesResponse []Role
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(ListRolesPath), &rolesResponse, nil, nil, withOrgID(orgID))
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return rolesResponse, annos, nil
+}
+
+// GetRole returns the RBAC role with the given UID in the given organization,
+// including its permissions.
+func (c *Client) GetRole(ctx context.Context, orgID, roleUID string) (*Role, annotations.Annotations, error) {
+	var roleResponse Role
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(RolePath, roleUID), &roleResponse, nil, nil, withOrgID(orgID))
+	if err != nil {
+		return nil, annos, err
 	}
 
-	return usersResponse, nextPage, nil
+	return &roleResponse, annos, nil
+}
+
+// ListRoleAssignments returns the users, service accounts, teams and built-in
+// roles an RBAC role is assigned to in the given organization.
+func (c *Client) ListRoleAssignments(ctx context.Context, orgID, roleUID string) (*RoleAssignments, annotations.Annotations, error) {
+	var assignmentsResponse RoleAssignments
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(RoleAssignmentsPath, roleUID), &assignmentsResponse, nil, nil, withOrgID(orgID))
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return &assignmentsResponse, annos, nil
+}
+
+// GetUser returns the user with the given ID.
+func (c *Client) GetUser(ctx context.Context, userID string) (*User, annotations.Annotations, error) {
+	var userResponse User
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(UserPath, userID), &userResponse, nil, nil)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return &userResponse, annos, nil
+}
+
+// GetSignedInUser returns the user the client's credentials belong to. For a
+// service account token this is the service account.
+func (c *Client) GetSignedInUser(ctx context.Context) (*User, annotations.Annotations, error) {
+	var userResponse User
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(SignedInUserPath), &userResponse, nil, nil)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return &userResponse, annos, nil
+}
+
+// ListUserOrgs returns the organizations the user is a member of, with their role in each.
+func (c *Client) ListUserOrgs(ctx context.Context, userID string) ([]UserOrg, annotations.Annotations, error) {
+	var orgsResponse []UserOrg
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(ListUserOrgsPath, userID), &orgsResponse, nil, nil)
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return orgsResponse, annos, nil
+}
+
+// AddOrgUser adds an existing user to the organization with the given role.
+// Grafana responds with 409 if the user is already a member.
+func (c *Client) AddOrgUser(ctx context.Context, orgID, loginOrEmail, role string) (annotations.Annotations, error) {
+	body := map[string]string{
+		"loginOrEmail": loginOrEmail,
+		"role":         role,
+	}
+
+	return c.doRequest(ctx, http.MethodPost, c.orgUsersURL(orgID), nil, body, nil)
+}
+
+// UpdateOrgUserRole changes the role of a member of the organization.
+func (c *Client) UpdateOrgUserRole(ctx context.Context, orgID, userID, role string) (annotations.Annotations, error) {
+	body := map[string]string{
+		"role": role,
+	}
+
+	return c.doRequest(ctx, http.MethodPatch, c.orgUserURL(orgID, userID), nil, body, nil)
+}
+
+// RemoveOrgUser removes a member from the organization.
+func (c *Client) RemoveOrgUser(ctx context.Context, orgID, userID string) (annotations.Annotations, error) {
+	return c.doRequest(ctx, http.MethodDelete, c.orgUserURL(orgID, userID), nil, nil, nil)
+}
+
+// orgUsersURL returns the URL of the members of the organization. The Orgs API
+// requires a Grafana server admin, so with a service account token the
+// members of the token's own organization are addressed instead.
+func (c *Client) orgUsersURL(orgID string) *url.URL {
+	if c.UsesServiceAccountToken() {
+		return c.buildResourceURL(CurrentOrgUsersPath)
+	}
+	return c.buildResourceURL(OrgUsersPath, orgID)
+}
+
+// orgUserURL returns the URL of a member of the organization, see orgUsersURL.
+func (c *Client) orgUserURL(orgID, userID string) *url.URL {
+	if c.UsesServiceAccountToken() {
+		return c.buildResourceURL(CurrentOrgUserPath, userID)
+	}
+	return c.buildResourceURL(OrgUserPath, orgID, userID)
+}
+
+// GetServiceAccount returns the service account with the given ID in the given organization.
+func (c *Client) GetServiceAccount(ctx context.Context, orgID, serviceAccountID string) (*ServiceAccount, annotations.Annotations, error) {
+	var serviceAccountResponse ServiceAccount
+
+	annos, err := c.doRequest(ctx, http.MethodGet, c.buildResourceURL(ServiceAccountPath, serviceAccountID), &serviceAccountResponse, nil, nil, withOrgID(orgID))
+	if err != nil {
+		return nil, annos, err
+	}
+
+	return &serviceAccountResponse, annos, nil
+}
+
+// UpdateServiceAccountRole changes the org role of a service account.
+func (c *Client) UpdateServiceAccountRole(ctx context.Context, orgID, serviceAccountID, role string) (annotations.Annotations, error) {
+	body := map[string]string{
+		"role": role,
+	}
+
+	return c.doRequest(ctx, http.MethodPatch, c.buildResourceURL(ServiceAccountPath, serviceAccountID), nil, body, nil, withOrgID(orgID))
+}
+
+// AddTeamMember adds a user or service account to the team.
+func (c *Client) AddTeamMember(ctx context.Context, orgID, teamID string, userID int) (annotations.Annotations, error) {
+	body := map[string]int{
+		"userId": userID,
+	}
+
+	return c.doRequest(ctx, http.MethodPost, c.buildResourceURL(ListTeamMembersPath, teamID), nil, body, nil, withOrgID(orgID))
+}
+
+// UpdateTeamMemberPermission sets the permission of a team member, e.g.
+// TeamPermissionAdmin to make the member a team admin.
+func (c *Client) UpdateTeamMemberPermission(ctx context.Context, orgID, teamID, userID string, permission TeamPermission) (annotations.Annotations, error) {
+	body := map[string]TeamPermission{
+		"permission": permission,
+	}
+
+	return c.doRequest(ctx, http.MethodPut, c.buildResourceURL(TeamMemberPath, teamID, userID), nil, body, nil, withOrgID(orgID))
+}
+
+// RemoveTeamMember removes a user or service account from the team.
+func (c *Client) RemoveTeamMember(ctx context.Context, orgID, teamID, userID string) (annotations.Annotations, error) {
+	return c.doRequest(ctx, http.MethodDelete, c.buildResourceURL(TeamMemberPath, teamID, userID), nil, nil, nil, withOrgID(orgID))
+}
+
+// SetUserPermission sets the managed permission of a user or service account on
+// a resource through the access control API, leaving the permissions of other
+// principals untouched. resource is the kind of resource, e.g. "folders" or
+// "dashboards", and an empty permission removes the user's permission.
+func (c *Client) SetUserPermission(ctx context.Context, orgID, resource, uid, userID, permission string) (annotations.Annotations, error) {
+	body := map[string]string{
+		"permission": permission,
+	}
+
+	return c.doRequest(ctx, http.MethodPost, c.buildResourceURL(UserPermissionPath, resource, uid, userID), nil, body, nil, withOrgID(orgID))
+}
+
+// SetTeamPermission sets the managed permission of a team on a resource through
+// the access control API. See SetUserPermission.
+func (c *Client) SetTeamPermission(ctx context.Context, orgID, resource, uid, teamID, permission string) (annotations.Annotations, error) {
+	body := map[string]string{
+		"permission": permission,
+	}
+
+	return c.doRequest(ctx, http.MethodPost, c.buildResourceURL(TeamPermissionPath, resource, uid, teamID), nil, body, nil, withOrgID(orgID))
+}
+
+// UpdateFolderPermissions replaces the permissions of a folder through the
+// legacy folder permissions API. items must hold every explicit entry the
+// folder should keep.
+func (c *Client) UpdateFolderPermissions(ctx context.Context, orgID, folderUID string, items []PermissionItem) (annotations.Annotations, error) {
+	body := map[string][]PermissionItem{
+		"items": items,
+	}
+
+	return c.doRequest(ctx, http.MethodPost, c.buildResourceURL(FolderPermissionPath, folderUID), nil, body, nil, withOrgID(orgID))
+}
+
+// UpdateDashboardPermissions replaces the permissions of a dashboard through the
+// legacy dashboard permissions API. See UpdateFolderPermissions.
+func (c *Client) UpdateDashboardPermissions(ctx context.Context, orgID, dashboardUID string, items []PermissionItem) (annotations.Annotations, error) {
+	body := map[string][]PermissionItem{
+		"items": items,
+	}
+
+	return c.doRequest(ctx, http.MethodPost, c.buildResourceURL(DashboardPermissionPath, dashboardUID), nil, body, nil, withOrgID(orgID))
+}
+
+// AddUserRole assigns an RBAC role to a user or service account in the given
+// organization. Other roles of the user are left untouched.
+func (c *Client) AddUserRole(ctx context.Context, orgID, userID, roleUID string) (annotations.Annotations, error) {
+	body := map[string]interface{}{
+		"roleUid": roleUID,
+		"global":  false,
+	}
+
+	return c.doRequest(ctx, http.MethodPost, c.buildResourceURL(UserRolesPath, userID), nil, body, nil, withOrgID(orgID))
+}
+
+// RemoveUserRole removes the assignment of an RBAC role from a user or service
+// account in the given organization.
+func (c *Client) RemoveUserRole(ctx context.Context, orgID, userID, roleUID string) (annotations.Annotations, error) {
+	return c.doRequest(ctx, http.MethodDelete, c.buildResourceURL(UserRolePath, userID, roleUID), nil, nil, nil, withOrgID(orgID))
+}
+
+// AddTeamRole assigns an RBAC role to a team in the given organization. Other
+// roles of the team are left untouched.
+func (c *Client) AddTeamRole(ctx context.Context, orgID, teamID, roleUID string) (annotations.Annotations, error) {
+	body := map[string]string{
+		"roleUid": roleUID,
+	}
+
+	return c.doRequest(ctx, http.MethodPost, c.buildResourceURL(TeamRolesPath, teamID), nil, body, nil, withOrgID(orgID))
+}
+
+// RemoveTeamRole removes the assignment of an RBAC role from a team in the given organization.
+func (c *Client) RemoveTeamRole(ctx context.Context, orgID, teamID, roleUID string) (annotations.Annotations, error) {
+	return c.doRequest(ctx, http.MethodDelete, c.buildResourceURL(TeamRolePath, teamID, roleUID), nil, nil, nil, withOrgID(orgID))
+}
+
+// UpdateUserServerAdmin grants or revokes the Grafana server admin permission of a user.
+func (c *Client) UpdateUserServerAdmin(ctx context.Context, userID string, isGrafanaAdmin bool) (annotations.Annotations, error) {
+	body := map[string]bool{
+		"isGrafanaAdmin": isGrafanaAdmin,
+	}
+
+	return c.doRequest(ctx, http.MethodPut, c.buildResourceURL(UserServerAdminPath, userID), nil, body, nil)
+}
+
+// CreateUser creates a local Grafana user with a password and returns its ID.
+// Grafana adds the new user to the default organization.
+func (c *Client) CreateUser(ctx context.Context, login, email, name, password string) (int, annotations.Annotations, error) {
+	var createResponse CreateUserResponse
+
+	body := map[string]string{
+		"login":    login,
+		"email":    email,
+		"name":     name,
+		"password": password,
+	}
+
+	annos, err := c.doRequest(ctx, http.MethodPost, c.buildResourceURL(AdminUsersPath), &createResponse, body, nil)
+	if err != nil {
+		return 0, annos, err
+	}
+
+	return createResponse.ID, annos, nil
+}
+
+// UpdateUserPassword sets the password of a local Grafana user.
+func (c *Client) UpdateUserPassword(ctx context.Context, userID, password string) (annotations.Annotations, error) {
+	body := map[string]string{
+		"password": password,
+	}
+
+	return c.doRequest(ctx, http.MethodPut, c.buildResourceURL(UserPasswordPath, userID), nil, body, nil)
 }
 
 func setupPagination(addr *url.URL, paginationVars *PaginationVars) *url.Values {
@@ -141,7 +815,50 @@ func setupPagination(addr *url.URL, paginationVars *PaginationVars) *url.Values
 	return &q
 }
 
+// orgIDHeader is the header Grafana resolves org-scoped endpoints against.
+const orgIDHeader = "X-Grafana-Org-Id"
+
+// withOrgID scopes a request to the given organization. Grafana resolves
+// org-scoped endpoints (teams, folders, data sources, ...) against the org in
+// the X-Grafana-Org-Id header instead of the credential's current org.
+func withOrgID(orgID string) uhttp.RequestOption {
+	return uhttp.WithHeader(orgIDHeader, orgID)
+}
+
+type uncachedContextKey struct{}
+
+// WithoutCache returns a context whose GET requests bypass the response cache.
+// Provisioning reads the current state through it before deciding whether to
+// change anything, since a response cached during sync may already be stale.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, uncachedContextKey{}, true)
+}
+
+func isUncached(ctx context.Context) bool {
+	uncached, _ := ctx.Value(uncachedContextKey{}).(bool)
+	return uncached
+}
+
+// scopeQueryToOrg copies the org of an org-scoped request into its orgId query
+// parameter, which Grafana accepts as well. The uhttp response cache keys GET
+// requests on their URL but not on the org header, so without it the same
+// endpoint would be served from the cache of another org.
+func scopeQueryToOrg(req *http.Request) {
+	orgID := req.Header.Get(orgIDHeader)
+	if orgID == "" {
+		return
+	}
+
+	q := req.URL.Query()
+	q.Set("orgId", orgID)
+	req.URL.RawQuery = q.Encode()
+}
+
 // doRequest handles HTTP requests with authentication and optional pagination.
+// Additional request options, such as withOrgID, are applied last. Rate-limited
+// and transient failures of idempotent requests are retried according to
+// c.retryPolicy. The returned annotations carry the rate limit state reported
+// by Grafana for the last attempt.
 func (c *Client) doRequest(
 	ctx context.Context,
 	method string,
@@ -149,46 +866,146 @@ func (c *Client) doRequest(
 	response interface{},
 	data interface{},
 	paginationVars *PaginationVars,
-) error {
-	var err error
-
+	extraOptions ...uhttp.RequestOption,
+) (annotations.Annotations, error) {
 	reqOptions := []uhttp.RequestOption{
 		uhttp.WithContentType("application/json"),
 		uhttp.WithAccept("application/json"),
 	}
 
 	// Set authentication method
-	authString := fmt.Sprintf("%s:%s", c.username, c.password)
-	authEncoded := base64.StdEncoding.EncodeToString([]byte(authString))
-	reqOptions = append(reqOptions, uhttp.WithHeader("Authorization", "Basic "+authEncoded))
+	if c.apiToken != "" {
+		reqOptions = append(reqOptions, uhttp.WithBearerToken(c.apiToken))
+	} else {
+		authString := fmt.Sprintf("%s:%s", c.username, c.password)
+		authEncoded := base64.StdEncoding.EncodeToString([]byte(authString))
+		reqOptions = append(reqOptions, uhttp.WithHeader("Authorization", "Basic "+authEncoded))
+	}
 
 	if data != nil {
 		reqOptions = append(reqOptions, uhttp.WithJSONBody(data))
 	}
 
+	reqOptions = append(reqOptions, extraOptions...)
+
 	q := setupPagination(urlAddress, paginationVars)
 	if q != nil {
 		urlAddress.RawQuery = q.Encode()
 	}
 
-	req, err := c.httpClient.NewRequest(ctx, method, urlAddress, reqOptions...)
-	if err != nil {
-		return err
-	}
-
 	doOptions := []uhttp.DoOption{}
 	if response != nil {
 		doOptions = append(doOptions, uhttp.WithJSONResponse(response))
 	}
 
-	resp, err := c.httpClient.Do(req, doOptions...)
+	l := ctxzap.Extract(ctx)
+
+	for attempt := 0; ; attempt++ {
+		// The request is rebuilt on every attempt so that the JSON body can be read again.
+		req, err := c.httpClient.NewRequest(ctx, method, urlAddress, reqOptions...)
+		if err != nil {
+			return nil, err
+		}
+		scopeQueryToOrg(req)
+
+		annos, err := c.send(req, doOptions...)
+		if err == nil {
+			return annos, nil
+		}
+
+		var resp *http.Response
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			resp = apiErr.response
+		}
+
+		delay, retry := c.retryPolicy.retryDelay(method, attempt, resp, err)
+		if !retry {
+			return annos, err
+		}
+
+		l.Debug(
+			"grafana-connector: retrying request",
+			zap.String("method", method),
+			zap.String("path", urlAddress.Path),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return annos, err
+		}
+	}
+}
+
+// send performs a single HTTP request and returns the rate limit state reported
+// by Grafana. Error responses are returned as *APIError.
+func (c *Client) send(req *http.Request, doOptions ...uhttp.DoOption) (annotations.Annotations, error) {
+	do := c.httpClient.Do
+	if req.Method == http.MethodGet && isUncached(req.Context()) {
+		do = c.doUncached
+	}
+
+	resp, err := do(req, doOptions...)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+
+	var annos annotations.Annotations
+	if resp != nil {
+		// Grafana only sends rate limit headers on some deployments; a parse error is not fatal.
+		if rateLimit, rlErr := ratelimit.ExtractRateLimitData(resp.StatusCode, &resp.Header); rlErr == nil && rateLimit != nil {
+			annos.WithRateLimiting(rateLimit)
+		}
+	}
+
 	if err != nil {
-		return err
+		// Surface Grafana error responses as typed errors carrying the HTTP status.
+		if resp != nil && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+			return annos, newAPIError(req.Method, req.URL.Path, resp, err)
+		}
+		return annos, err
 	}
 
+	return annos, nil
+}
+
+// doUncached performs a request without consulting or filling the uhttp
+// response cache. Like uhttp, it buffers the body, applies doOptions and
+// reports non-2xx responses as an error.
+func (c *Client) doUncached(req *http.Request, doOptions ...uhttp.DoOption) (*http.Response, error) {
+	resp, err := c.httpClient.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
 	defer resp.Body.Close()
 
-	return nil
+	body, err := io.ReadAll(resp.Body)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	wresp := &uhttp.WrapperResponse{
+		Header:     resp.Header,
+		Body:       body,
+		Status:     resp.Status,
+		StatusCode: resp.StatusCode,
+	}
+
+	var errs []error
+	for _, option := range doOptions {
+		if err := option(wresp); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return resp, errors.Join(errs...)
 }
 
 // Convert UserByOrg to User.
@@ -0,0 +1,446 @@
+package grafana
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestBuildResourceURL(t *testing.T) {
+	testCases := []struct {
+		name         string
+		hostname     string
+		pathTemplate string
+		args         []interface{}
+		expected     string
+	}{
+		{
+			name:         "root path",
+			hostname:     "https://grafana.example.com",
+			pathTemplate: SearchUsersPath,
+			expected:     "https://grafana.example.com/api/users/search",
+		},
+		{
+			name:         "root path with trailing slash",
+			hostname:     "https://grafana.example.com/",
+			pathTemplate: SearchUsersPath,
+			expected:     "https://grafana.example.com/api/users/search",
+		},
+		{
+			name:         "sub-path",
+			hostname:     "https://corp.example.com/grafana",
+			pathTemplate: SearchUsersPath,
+			expected:     "https://corp.example.com/grafana/api/users/search",
+		},
+		{
+			name:         "sub-path with trailing slash",
+			hostname:     "https://corp.example.com/grafana/",
+			pathTemplate: SearchUsersPath,
+			expected:     "https://corp.example.com/grafana/api/users/search",
+		},
+		{
+			name:         "nested sub-path with multiple trailing slashes",
+			hostname:     "https://corp.example.com/tools/grafana//",
+			pathTemplate: ListOrgsPath,
+			expected:     "https://corp.example.com/tools/grafana/api/orgs",
+		},
+		{
+			name:         "port",
+			hostname:     "http://localhost:3000",
+			pathTemplate: SearchUsersInOrgPath,
+			args:         []interface{}{"42"},
+			expected:     "http://localhost:3000/api/orgs/42/users/search",
+		},
+		{
+			name:         "port and sub-path",
+			hostname:     "http://10.0.0.5:8080/grafana/",
+			pathTemplate: SearchUsersInOrgPath,
+			args:         []interface{}{"7"},
+			expected:     "http://10.0.0.5:8080/grafana/api/orgs/7/users/search",
+		},
+		{
+			name:         "query string in template",
+			hostname:     "https://corp.example.com/grafana/",
+			pathTemplate: "/api/search?type=dash-folder",
+			expected:     "https://corp.example.com/grafana/api/search?type=dash-folder",
+		},
+		{
+			name:         "query string in template and hostname",
+			hostname:     "https://corp.example.com/grafana/?orgId=1",
+			pathTemplate: "/api/search?type=dash-folder",
+			expected:     "https://corp.example.com/grafana/api/search?orgId=1&type=dash-folder",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			baseUrl, err := parseBaseURL(tc.hostname)
+			if err != nil {
+				t.Fatalf("parseBaseURL(%q) returned error: %v", tc.hostname, err)
+			}
+
+			c := &Client{baseUrl: baseUrl}
+			actual := c.buildResourceURL(tc.pathTemplate, tc.args...).String()
+			if actual != tc.expected {
+				t.Errorf("buildResourceURL(%q) = %q, expected %q", tc.pathTemplate, actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestBuildResourceURLDoesNotMutateBaseURL(t *testing.T) {
+	baseUrl, err := parseBaseURL("https://corp.example.com/grafana/")
+	if err != nil {
+		t.Fatalf("parseBaseURL returned error: %v", err)
+	}
+
+	c := &Client{baseUrl: baseUrl}
+	_ = c.buildResourceURL(SearchUsersPath)
+	_ = c.buildResourceURL(ListOrgsPath)
+
+	if c.baseUrl.String() != "https://corp.example.com/grafana" {
+		t.Errorf("base URL was mutated: %q", c.baseUrl.String())
+	}
+}
+
+func TestParseBaseURLInvalid(t *testing.T) {
+	for _, hostname := range []string{"", "grafana.example.com", "://bad"} {
+		if _, err := parseBaseURL(hostname); err == nil {
+			t.Errorf("parseBaseURL(%q) expected error", hostname)
+		}
+	}
+}
+
+func TestNextSearchPage(t *testing.T) {
+	testCases := []struct {
+		name          string
+		page          uint64
+		perPage       int
+		requestedSize uint64
+		totalCount    int
+		expected      uint64
+	}{
+		{name: "first of several pages", page: 1, perPage: 50, requestedSize: 50, totalCount: 120, expected: 2},
+		{name: "last partial page", page: 3, perPage: 50, requestedSize: 50, totalCount: 120, expected: 0},
+		{name: "exact multiple ends without extra request", page: 2, perPage: 50, requestedSize: 50, totalCount: 100, expected: 0},
+		{name: "grafana capped perpage", page: 1, perPage: 10, requestedSize: 50, totalCount: 25, expected: 2},
+		{name: "perpage missing from response", page: 1, perPage: 0, requestedSize: 50, totalCount: 60, expected: 2},
+		{name: "empty result", page: 1, perPage: 50, requestedSize: 50, totalCount: 0, expected: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := nextSearchPage(tc.page, tc.perPage, tc.requestedSize, tc.totalCount)
+			if actual != tc.expected {
+				t.Errorf("nextSearchPage() = %d, expected %d", actual, tc.expected)
+			}
+		})
+	}
+}
+
+func TestListUsersUsesSearch(t *testing.T) {
+	var gotQuery url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != SearchUsersPath {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		gotQuery = r.URL.Query()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"totalCount":3,"page":1,"perPage":2,"users":[{"id":1,"login":"admin"},{"id":2,"login":"viewer"}]}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, "admin", "admin", "")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	users, nextPage, _, err := client.ListUsers(ctx, &PaginationVars{Size: 2}, "adm")
+	if err != nil {
+		t.Fatalf("ListUsers returned error: %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("expected 2 users, got %d", len(users))
+	}
+	if nextPage != 2 {
+		t.Errorf("expected next page 2, got %d", nextPage)
+	}
+	if gotQuery.Get("page") != "1" || gotQuery.Get("perpage") != "2" || gotQuery.Get("query") != "adm" {
+		t.Errorf("unexpected query parameters: %v", gotQuery)
+	}
+}
+
+func TestListUsersByOrgPaginates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/orgs/1/users/search" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Query().Get("page") {
+		case "1":
+			_, _ = w.Write([]byte(`{"totalCount":3,"page":1,"perPage":2,"orgUsers":[{"userId":1,"role":"Admin"},{"userId":2,"role":"Viewer"}]}`))
+		case "2":
+			_, _ = w.Write([]byte(`{"totalCount":3,"page":2,"perPage":2,"orgUsers":[{"userId":3,"role":"Editor"}]}`))
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, "admin", "admin", "")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	var userIDs []int
+	page := uint64(0)
+	for {
+		users, nextPage, _, err := client.ListUsersByOrg(ctx, "1", &PaginationVars{Size: 2, Page: page})
+		if err != nil {
+			t.Fatalf("ListUsersByOrg returned error: %v", err)
+		}
+		for _, u := range users {
+			userIDs = append(userIDs, u.ID)
+		}
+		if nextPage == 0 {
+			break
+		}
+		page = nextPage
+	}
+
+	if len(userIDs) != 3 {
+		t.Errorf("expected 3 users across pages, got %v", userIDs)
+	}
+}
+
+func TestListTeamsScopesRequestToOrg(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != SearchTeamsPath {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if got := r.Header.Get("X-Grafana-Org-Id"); got != "3" {
+			t.Errorf("expected X-Grafana-Org-Id 3, got %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"totalCount":1,"page":1,"perPage":50,"teams":[{"id":7,"orgId":3,"name":"Platform","memberCount":2}]}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, "admin", "admin", "")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	teams, nextPage, _, err := client.ListTeams(ctx, "3", &PaginationVars{Size: 50})
+	if err != nil {
+		t.Fatalf("ListTeams returned error: %v", err)
+	}
+	if len(teams) != 1 || teams[0].Name != "Platform" {
+		t.Errorf("unexpected teams: %v", teams)
+	}
+	if nextPage != 0 {
+		t.Errorf("expected no next page, got %d", nextPage)
+	}
+}
+
+func TestListTeamsDoesNotShareCacheAcrossOrgs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Header.Get("X-Grafana-Org-Id") {
+		case "1":
+			_, _ = w.Write([]byte(`{"totalCount":1,"page":1,"perPage":50,"teams":[{"id":7,"orgId":1,"name":"Platform"}]}`))
+		case "2":
+			_, _ = w.Write([]byte(`{"totalCount":1,"page":1,"perPage":50,"teams":[{"id":8,"orgId":2,"name":"Support"}]}`))
+		default:
+			t.Errorf("unexpected X-Grafana-Org-Id %q", r.Header.Get("X-Grafana-Org-Id"))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, "admin", "admin", "")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	for _, expected := range []struct{ orgID, team string }{{"1", "Platform"}, {"2", "Support"}} {
+		teams, _, _, err := client.ListTeams(ctx, expected.orgID, &PaginationVars{Size: 50})
+		if err != nil {
+			t.Fatalf("ListTeams returned error: %v", err)
+		}
+		if len(teams) != 1 || teams[0].Name != expected.team {
+			t.Errorf("org %s: expected team %s, got %v", expected.orgID, expected.team, teams)
+		}
+	}
+}
+
+func TestServiceAccountTokenMode(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer glsa_token" {
+			t.Errorf("expected the token as Bearer authorization, got %q", got)
+		}
+		paths = append(paths, r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/org":
+			_, _ = w.Write([]byte(`{"id":2,"name":"Platform"}`))
+		case "/api/org/users/search":
+			if got := r.URL.Query().Get("query"); got != "alice" {
+				t.Errorf("expected query alice, got %q", got)
+			}
+			_, _ = w.Write([]byte(`{"totalCount":1,"page":1,"perPage":50,"orgUsers":[{"orgId":2,"userId":5,"login":"alice","email":"alice@example.com","role":"Editor"}]}`))
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, "", "", "glsa_token")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	orgs, next, _, err := client.ListOrganizations(ctx, &PaginationVars{Size: 50})
+	if err != nil {
+		t.Fatalf("ListOrganizations returned error: %v", err)
+	}
+	if len(orgs) != 1 || orgs[0].ID != 2 || next != 0 {
+		t.Errorf("expected only the organization of the service account, got %v (next page %d)", orgs, next)
+	}
+
+	users, next, _, err := client.ListUsers(ctx, &PaginationVars{Size: 50}, "alice")
+	if err != nil {
+		t.Fatalf("ListUsers returned error: %v", err)
+	}
+	if len(users) != 1 || users[0].ID != 5 || users[0].Login != "alice" || next != 0 {
+		t.Errorf("expected the members of the service account's organization, got %v (next page %d)", users, next)
+	}
+
+	if len(paths) != 2 {
+		t.Errorf("expected 2 requests, got %v", paths)
+	}
+}
+
+func TestBasicAuthMode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "admin" || password != "secret" {
+			t.Errorf("expected basic auth as admin, got %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Path != ListOrgsPath {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"id":1,"name":"Main Org."},{"id":2,"name":"Platform"}]`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, "admin", "secret", "")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	orgs, _, _, err := client.ListOrganizations(ctx, &PaginationVars{Size: 50})
+	if err != nil {
+		t.Fatalf("ListOrganizations returned error: %v", err)
+	}
+	if len(orgs) != 2 {
+		t.Errorf("expected every organization, got %v", orgs)
+	}
+}
+
+func TestWithoutCacheReadsCurrentState(t *testing.T) {
+	members := `[]`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/teams/8/members" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Team not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(members))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, "admin", "admin", "")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	if _, _, err := client.ListTeamMembers(ctx, "1", "7"); err != nil {
+		t.Fatalf("ListTeamMembers returned error: %v", err)
+	}
+	members = `[{"userId":2,"login":"alice"}]`
+
+	cached, _, err := client.ListTeamMembers(ctx, "1", "7")
+	if err != nil {
+		t.Fatalf("ListTeamMembers returned error: %v", err)
+	}
+	if len(cached) != 0 {
+		t.Errorf("expected the cached member list, got %v", cached)
+	}
+
+	current, _, err := client.ListTeamMembers(WithoutCache(ctx), "1", "7")
+	if err != nil {
+		t.Fatalf("ListTeamMembers returned error: %v", err)
+	}
+	if len(current) != 1 || current[0].Login != "alice" {
+		t.Errorf("expected the current member list, got %v", current)
+	}
+
+	_, _, err = client.ListTeamMembers(WithoutCache(ctx), "1", "8")
+	if !IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestListDashboardsFiltersByFolder(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/search" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("type"); got != "dash-db" {
+			t.Errorf("expected type dash-db, got %q", got)
+		}
+		queries = append(queries, r.URL.Query().Get("folderUIDs")+"|"+r.URL.Query().Get("folderIds"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"id":1,"uid":"abc","title":"Overview"},{"id":2,"uid":"def","title":"Latency"}]`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, "admin", "admin", "")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	dashboards, nextPage, _, err := client.ListDashboards(ctx, "1", "platform", &PaginationVars{Size: 2})
+	if err != nil {
+		t.Fatalf("ListDashboards returned error: %v", err)
+	}
+	if len(dashboards) != 2 || nextPage != 2 {
+		t.Errorf("expected 2 dashboards and next page 2, got %d and %d", len(dashboards), nextPage)
+	}
+
+	if _, _, _, err := client.ListDashboards(ctx, "1", "", &PaginationVars{Size: 50}); err != nil {
+		t.Fatalf("ListDashboards returned error: %v", err)
+	}
+
+	expected := []string{"platform|", "|0"}
+	for i, q := range expected {
+		if queries[i] != q {
+			t.Errorf("request %d: folder filter %q, expected %q", i, queries[i], q)
+		}
+	}
+}
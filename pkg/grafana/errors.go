@@ -0,0 +1,175 @@
+package grafana
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/ratelimit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIError is returned by the client when Grafana responds with a non-2xx
+// status code. It captures the HTTP status, the endpoint that was called and
+// the fields of Grafana's JSON error body, and maps the status to a gRPC code
+// so that the Baton runtime can tell credential problems from outages.
+type APIError struct {
+	StatusCode int
+	Method     string
+	Endpoint   string
+
+	// Fields decoded from the Grafana error body, if present.
+	Message   string
+	MessageID string
+	TraceID   string
+
+	// RateLimit is the rate limit state reported with the response, if any.
+	RateLimit *v2.RateLimitDescription
+
+	// Err is the underlying error reported by the HTTP client.
+	Err error
+
+	response *http.Response
+}
+
+// apiErrorBody is the JSON error body returned by the Grafana API.
+type apiErrorBody struct {
+	Message   string `json:"message"`
+	MessageID string `json:"messageId"`
+	TraceID   string `json:"traceID"`
+}
+
+// newAPIError builds an APIError from a failed response. The response body has
+// already been read by uhttp and replaced with an in-memory reader, so it can be
+// decoded here without affecting the caller.
+func newAPIError(method, endpoint string, resp *http.Response, err error) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Method:     method,
+		Endpoint:   endpoint,
+		Err:        err,
+		response:   resp,
+	}
+
+	if rateLimit, rlErr := ratelimit.ExtractRateLimitData(resp.StatusCode, &resp.Header); rlErr == nil {
+		apiErr.RateLimit = rateLimit
+	}
+
+	if resp.Body == nil {
+		return apiErr
+	}
+
+	body, readErr := io.ReadAll(resp.Body)
+	if readErr != nil || len(body) == 0 {
+		return apiErr
+	}
+
+	var errBody apiErrorBody
+	if json.Unmarshal(body, &errBody) == nil {
+		apiErr.Message = errBody.Message
+		apiErr.MessageID = errBody.MessageID
+		apiErr.TraceID = errBody.TraceID
+	}
+
+	return apiErr
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "grafana API error: %s %s returned status %d", e.Method, e.Endpoint, e.StatusCode)
+
+	if e.Message != "" {
+		fmt.Fprintf(&sb, ": %s", e.Message)
+	}
+	if e.MessageID != "" {
+		fmt.Fprintf(&sb, " (messageId: %s)", e.MessageID)
+	}
+	if e.TraceID != "" {
+		fmt.Fprintf(&sb, " (traceID: %s)", e.TraceID)
+	}
+
+	return sb.String()
+}
+
+// Unwrap returns the underlying HTTP client error.
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Code maps the HTTP status code to a gRPC status code.
+func (e *APIError) Code() codes.Code {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	}
+
+	if e.StatusCode >= 500 && e.StatusCode <= 599 {
+		return codes.Unavailable
+	}
+
+	return codes.Unknown
+}
+
+// GRPCStatus allows status.FromError and status.Code to extract the mapped code,
+// even when the APIError is wrapped with fmt.Errorf("...: %w", err). The rate
+// limit state is attached as a status detail so the Baton runtime can back off.
+func (e *APIError) GRPCStatus() *status.Status {
+	st := status.New(e.Code(), e.Error())
+	if e.RateLimit != nil {
+		if withDetails, err := st.WithDetails(e.RateLimit); err == nil {
+			st = withDetails
+		}
+	}
+	return st
+}
+
+// hasStatus reports whether err is an APIError with the given status code.
+func hasStatus(err error, statusCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == statusCode
+}
+
+// IsNotFound reports whether err is an APIError for a 404 response, e.g. an
+// endpoint that is not available on the running Grafana edition.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsBadRequest reports whether err is an APIError for a 400 response.
+func IsBadRequest(err error) bool {
+	return hasStatus(err, http.StatusBadRequest)
+}
+
+// IsConflict reports whether err is an APIError for a 409 response, e.g. when
+// adding a user that is already a member.
+func IsConflict(err error) bool {
+	return hasStatus(err, http.StatusConflict)
+}
+
+// IsGone reports whether err is an APIError for a 410 response, which Grafana
+// returns for endpoints of removed features.
+func IsGone(err error) bool {
+	return hasStatus(err, http.StatusGone)
+}
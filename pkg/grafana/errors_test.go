@@ -0,0 +1,129 @@
+package grafana
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAPIErrorFromResponse(t *testing.T) {
+	testCases := []struct {
+		name       string
+		statusCode int
+		body       string
+		expected   codes.Code
+	}{
+		{
+			name:       "unauthorized",
+			statusCode: http.StatusUnauthorized,
+			body:       `{"message":"invalid API key","messageId":"auth.invalid-key","traceID":"00f1"}`,
+			expected:   codes.Unauthenticated,
+		},
+		{
+			name:       "forbidden",
+			statusCode: http.StatusForbidden,
+			body:       `{"message":"Permission denied","messageId":"accesscontrol.denied","traceID":"00f2"}`,
+			expected:   codes.PermissionDenied,
+		},
+		{
+			name:       "not found",
+			statusCode: http.StatusNotFound,
+			body:       `{"message":"Not found"}`,
+			expected:   codes.NotFound,
+		},
+		{
+			name:       "too many requests",
+			statusCode: http.StatusTooManyRequests,
+			body:       `{"message":"Too many requests"}`,
+			expected:   codes.ResourceExhausted,
+		},
+		{
+			name:       "internal server error",
+			statusCode: http.StatusInternalServerError,
+			body:       `{"message":"Internal Server Error","traceID":"00f3"}`,
+			expected:   codes.Unavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.statusCode)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			client, err := NewClient(ctx, server.URL, "admin", "admin", "", WithRetryPolicy(RetryPolicy{}))
+			if err != nil {
+				t.Fatalf("NewClient returned error: %v", err)
+			}
+
+			_, _, _, err = client.ListOrganizations(ctx, &PaginationVars{Size: 1})
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+
+			// Wrap the error like the syncers do to check that the code survives.
+			err = fmt.Errorf("grafana-connector: failed to list organizations: %w", err)
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("expected *APIError, got %T: %v", err, err)
+			}
+			if apiErr.StatusCode != tc.statusCode {
+				t.Errorf("StatusCode = %d, expected %d", apiErr.StatusCode, tc.statusCode)
+			}
+			if apiErr.Endpoint != ListOrgsPath {
+				t.Errorf("Endpoint = %q, expected %q", apiErr.Endpoint, ListOrgsPath)
+			}
+			if apiErr.Message == "" {
+				t.Error("expected Message to be decoded from the response body")
+			}
+			if code := status.Code(err); code != tc.expected {
+				t.Errorf("status.Code = %s, expected %s", code, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAPIErrorFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"Permission denied","messageId":"accesscontrol.denied","traceID":"abc123"}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, "", "", "glsa_token")
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	_, _, _, err = client.ListUsers(ctx, &PaginationVars{Size: 1}, "")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %T: %v", err, err)
+	}
+	if apiErr.Method != http.MethodGet {
+		t.Errorf("Method = %q, expected %q", apiErr.Method, http.MethodGet)
+	}
+	if apiErr.Message != "Permission denied" {
+		t.Errorf("Message = %q", apiErr.Message)
+	}
+	if apiErr.MessageID != "accesscontrol.denied" {
+		t.Errorf("MessageID = %q", apiErr.MessageID)
+	}
+	if apiErr.TraceID != "abc123" {
+		t.Errorf("TraceID = %q", apiErr.TraceID)
+	}
+}
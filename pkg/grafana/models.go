@@ -2,6 +2,7 @@ package grafana
 
 import (
 	"net/url"
+	"time"
 
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 )
@@ -13,6 +14,9 @@ type Client struct {
 
 	username string
 	password string
+	apiToken string
+
+	retryPolicy RetryPolicy
 }
 
 type Organization struct {
@@ -21,17 +25,41 @@ type Organization struct {
 }
 
 type User struct {
-	ID            int      `json:"id"`
-	UID           string   `json:"uid"`
-	Name          string   `json:"name"`
-	Login         string   `json:"login"`
-	Email         string   `json:"email"`
-	AvatarUrl     string   `json:"avatarUrl"`
-	IsAdmin       bool     `json:"isAdmin"`
-	IsDisabled    bool     `json:"isDisabled"`
-	LastSeenAt    string   `json:"lastSeenAt"`
-	LastSeenAtAge string   `json:"lastSeenAtAge"`
-	AuthLabels    []string `json:"authLabels"`
+	ID             int      `json:"id"`
+	UID            string   `json:"uid"`
+	Name           string   `json:"name"`
+	Login          string   `json:"login"`
+	Email          string   `json:"email"`
+	AvatarUrl      string   `json:"avatarUrl"`
+	IsAdmin        bool     `json:"isAdmin"`
+	IsGrafanaAdmin bool     `json:"isGrafanaAdmin"`
+	IsDisabled     bool     `json:"isDisabled"`
+	LastSeenAt     string   `json:"lastSeenAt"`
+	LastSeenAtAge  string   `json:"lastSeenAtAge"`
+	AuthLabels     []string `json:"authLabels"`
+
+	IsExternal         bool `json:"isExternal"`
+	IsExternallySynced bool `json:"isExternallySynced"`
+}
+
+// IsServerAdmin reports whether the user is a Grafana server admin. The user
+// search reports it as isAdmin, while single-user lookups use isGrafanaAdmin.
+func (u *User) IsServerAdmin() bool {
+	return u.IsAdmin || u.IsGrafanaAdmin
+}
+
+// CreateUserResponse is the response of /api/admin/users.
+type CreateUserResponse struct {
+	ID      int    `json:"id"`
+	Message string `json:"message"`
+}
+
+// UserSearchResponse is the paginated response of /api/users/search.
+type UserSearchResponse struct {
+	TotalCount int    `json:"totalCount"`
+	Users      []User `json:"users"`
+	Page       int    `json:"page"`
+	PerPage    int    `json:"perPage"`
 }
 
 type UserByOrgResponse struct {
@@ -49,6 +77,229 @@ type UserByOrgResponse struct {
 	IsExternallySynced bool     `json:"isExternallySynced"`
 }
 
+// UserOrg is an organization membership of a user.
+type UserOrg struct {
+	OrgID int    `json:"orgId"`
+	Name  string `json:"name"`
+	Role  string `json:"role"`
+}
+
+// OrgUserSearchResponse is the paginated response of /api/orgs/:orgId/users/search.
+type OrgUserSearchResponse struct {
+	TotalCount int                 `json:"totalCount"`
+	OrgUsers   []UserByOrgResponse `json:"orgUsers"`
+	Page       int                 `json:"page"`
+	PerPage    int                 `json:"perPage"`
+}
+
+// TeamPermission is the permission level of a team member.
+type TeamPermission int
+
+const (
+	TeamPermissionMember TeamPermission = 0
+	TeamPermissionAdmin  TeamPermission = 4
+)
+
+type Team struct {
+	ID          int    `json:"id"`
+	UID         string `json:"uid"`
+	OrgID       int    `json:"orgId"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	AvatarUrl   string `json:"avatarUrl"`
+	MemberCount int    `json:"memberCount"`
+}
+
+// TeamSearchResponse is the paginated response of /api/teams/search.
+type TeamSearchResponse struct {
+	TotalCount int    `json:"totalCount"`
+	Teams      []Team `json:"teams"`
+	Page       int    `json:"page"`
+	PerPage    int    `json:"perPage"`
+}
+
+type TeamMember struct {
+	OrgID      int            `json:"orgId"`
+	TeamID     int            `json:"teamId"`
+	UserID     int            `json:"userId"`
+	Email      string         `json:"email"`
+	Name       string         `json:"name"`
+	Login      string         `json:"login"`
+	AuthModule string         `json:"auth_module"`
+	Permission TeamPermission `json:"permission"`
+}
+
+// Folder is a folder returned by /api/folders. ParentUID is only set for
+// nested folders (Grafana 10+).
+type Folder struct {
+	ID        int    `json:"id"`
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	ParentUID string `json:"parentUid"`
+}
+
+// Dashboard is a dashboard hit returned by /api/search?type=dash-db.
+type Dashboard struct {
+	ID          int      `json:"id"`
+	UID         string   `json:"uid"`
+	Title       string   `json:"title"`
+	URL         string   `json:"url"`
+	FolderUID   string   `json:"folderUid"`
+	FolderTitle string   `json:"folderTitle"`
+	Tags        []string `json:"tags"`
+}
+
+// PermissionLevel is the level of a folder or dashboard permission entry.
+type PermissionLevel int
+
+const (
+	PermissionView  PermissionLevel = 1
+	PermissionEdit  PermissionLevel = 2
+	PermissionAdmin PermissionLevel = 4
+)
+
+// ResourcePermission is a permission entry of a folder or dashboard. Exactly
+// one of UserID, TeamID or Role identifies the principal.
+type ResourcePermission struct {
+	UserID         int             `json:"userId"`
+	UserLogin      string          `json:"userLogin"`
+	UserEmail      string          `json:"userEmail"`
+	TeamID         int             `json:"teamId"`
+	Team           string          `json:"team"`
+	Role           string          `json:"role"`
+	Permission     PermissionLevel `json:"permission"`
+	PermissionName string          `json:"permissionName"`
+	Inherited      bool            `json:"inherited"`
+}
+
+// PermissionItem is an entry of the legacy folder and dashboard permissions
+// update API. Exactly one of UserID, TeamID or Role identifies the principal.
+type PermissionItem struct {
+	UserID     int             `json:"userId,omitempty"`
+	TeamID     int             `json:"teamId,omitempty"`
+	Role       string          `json:"role,omitempty"`
+	Permission PermissionLevel `json:"permission"`
+}
+
+// ServiceAccount is a Grafana service account. Service accounts belong to a
+// single organization and share the ID space of users.
+type ServiceAccount struct {
+	ID         int    `json:"id"`
+	UID        string `json:"uid"`
+	Name       string `json:"name"`
+	Login      string `json:"login"`
+	OrgID      int    `json:"orgId"`
+	IsDisabled bool   `json:"isDisabled"`
+	Role       string `json:"role"`
+	Tokens     int    `json:"tokens"`
+	AvatarUrl  string `json:"avatarUrl"`
+}
+
+// ServiceAccountSearchResponse is the paginated response of /api/serviceaccounts/search.
+type ServiceAccountSearchResponse struct {
+	TotalCount      int              `json:"totalCount"`
+	ServiceAccounts []ServiceAccount `json:"serviceAccounts"`
+	Page            int              `json:"page"`
+	PerPage         int              `json:"perPage"`
+}
+
+// ServiceAccountToken is a token of a Grafana service account. Expiration is
+// nil for tokens that never expire.
+type ServiceAccountToken struct {
+	ID                     int        `json:"id"`
+	Name                   string     `json:"name"`
+	Created                *time.Time `json:"created"`
+	LastUsedAt             *time.Time `json:"lastUsedAt"`
+	Expiration             *time.Time `json:"expiration"`
+	SecondsUntilExpiration *float64   `json:"secondsUntilExpiration"`
+	HasExpired             bool       `json:"hasExpired"`
+	IsRevoked              *bool      `json:"isRevoked"`
+}
+
+// APIKey is a legacy Grafana API key. Expiration is nil for keys that never expire.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	Expiration *time.Time `json:"expiration"`
+}
+
+// APIKeyMigrationStatus reports whether the legacy API keys of an organization
+// have been migrated to service accounts.
+type APIKeyMigrationStatus struct {
+	Migrated bool `json:"migrated"`
+}
+
+// DataSource is a Grafana data source.
+type DataSource struct {
+	ID        int    `json:"id"`
+	UID       string `json:"uid"`
+	OrgID     int    `json:"orgId"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	Access    string `json:"access"`
+	IsDefault bool   `json:"isDefault"`
+	ReadOnly  bool   `json:"readOnly"`
+}
+
+// DataSourcePermission is an entry of the access control permissions of a
+// data source (Grafana Enterprise and Cloud). Exactly one of UserID, TeamID or
+// BuiltInRole identifies the principal; Permission is "Query", "Edit" or "Admin".
+type DataSourcePermission struct {
+	ID               int      `json:"id"`
+	RoleName         string   `json:"roleName"`
+	IsManaged        bool     `json:"isManaged"`
+	IsInherited      bool     `json:"isInherited"`
+	IsServiceAccount bool     `json:"isServiceAccount"`
+	UserID           int      `json:"userId"`
+	UserLogin        string   `json:"userLogin"`
+	TeamID           int      `json:"teamId"`
+	Team             string   `json:"team"`
+	BuiltInRole      string   `json:"builtInRole"`
+	Actions          []string `json:"actions"`
+	Permission       string   `json:"permission"`
+}
+
+// Role is a Grafana RBAC role (Grafana Enterprise and Cloud). Fixed and basic
+// roles are global, custom roles belong to an organization.
+type Role struct {
+	Version     int              `json:"version"`
+	UID         string           `json:"uid"`
+	Name        string           `json:"name"`
+	DisplayName string           `json:"displayName"`
+	Description string           `json:"description"`
+	Group       string           `json:"group"`
+	Global      bool             `json:"global"`
+	Hidden      bool             `json:"hidden"`
+	Permissions []RolePermission `json:"permissions"`
+}
+
+// RolePermission is an action on a scope granted by an RBAC role.
+type RolePermission struct {
+	Action string `json:"action"`
+	Scope  string `json:"scope"`
+}
+
+// RoleAssignments lists the principals an RBAC role is assigned to.
+type RoleAssignments struct {
+	RoleUID string `json:"roleUID"`
+	Users   []struct {
+		UserID int  `json:"userId"`
+		Global bool `json:"global"`
+	} `json:"users"`
+	ServiceAccounts []struct {
+		ServiceAccountID int `json:"serviceAccountId"`
+	} `json:"serviceAccounts"`
+	Teams []struct {
+		TeamID int `json:"teamId"`
+	} `json:"teams"`
+	BuiltInRoles []struct {
+		BuiltInRole string `json:"builtInRole"`
+		Global      bool   `json:"global"`
+	} `json:"builtInRoles"`
+}
+
 // PaginationVars holds pagination parameters for API requests.
 type PaginationVars struct {
 	Size uint64
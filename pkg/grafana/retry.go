@@ -0,0 +1,146 @@
+package grafana
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy controls how the client retries requests that were rate limited
+// (HTTP 429) or failed with a transient server error (HTTP 502, 503 or 504).
+// Only idempotent methods are retried.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the initial attempt. Zero disables retries.
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles on every attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A Retry-After longer than MaxDelay is not waited
+	// for; the error is returned so the Baton runtime can back off instead.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used when none is configured.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: 5,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   60 * time.Second,
+	}
+}
+
+// ClientOption configures optional behaviour of the Client.
+type ClientOption func(*Client)
+
+// WithRetryPolicy overrides the default retry policy of the Client.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retryPolicy = policy
+	}
+}
+
+// isIdempotentMethod reports whether a request with the given method can be
+// safely sent again.
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isRetryableResponse reports whether a failed request is worth retrying. resp
+// is nil when the request failed before a response was received.
+func isRetryableResponse(resp *http.Response, err error) bool {
+	if resp == nil {
+		// uhttp reports timeouts, connection resets and unexpected EOFs as Unavailable.
+		return status.Code(err) == codes.Unavailable
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter returns the delay requested by a Retry-After header, given
+// either as a number of seconds or as an HTTP date.
+func parseRetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		delay := t.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// backoff returns the exponential backoff with jitter for the given retry
+// attempt (starting at 0). The result is between half and the full capped delay.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 32 {
+		if d := p.BaseDelay << attempt; d > 0 && d < p.MaxDelay {
+			delay = d
+		}
+	}
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+
+	return half + rand.N(half+1) //nolint:gosec // jitter does not need a cryptographic source
+}
+
+// retryDelay decides whether a failed attempt should be retried and, if so,
+// how long to wait first.
+func (p RetryPolicy) retryDelay(method string, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= p.MaxRetries || !isIdempotentMethod(method) || !isRetryableResponse(resp, err) {
+		return 0, false
+	}
+
+	if resp != nil {
+		if delay, ok := parseRetryAfter(resp.Header, time.Now()); ok {
+			if delay > p.MaxDelay {
+				return 0, false
+			}
+			return delay, true
+		}
+	}
+
+	return p.backoff(attempt), true
+}
+
+// sleepContext waits for the given duration or until the context is done.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
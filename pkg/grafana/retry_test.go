@@ -0,0 +1,211 @@
+package grafana
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  time.Millisecond,
+	MaxDelay:   50 * time.Millisecond,
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{name: "missing", value: "", ok: false},
+		{name: "seconds", value: "3", expected: 3 * time.Second, ok: true},
+		{name: "zero seconds", value: "0", expected: 0, ok: true},
+		{name: "negative seconds", value: "-1", ok: false},
+		{name: "http date", value: "Wed, 01 Jan 2025 12:00:10 GMT", expected: 10 * time.Second, ok: true},
+		{name: "http date in the past", value: "Wed, 01 Jan 2025 11:00:00 GMT", expected: 0, ok: true},
+		{name: "garbage", value: "soon", ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			if tc.value != "" {
+				header.Set("Retry-After", tc.value)
+			}
+
+			delay, ok := parseRetryAfter(header, now)
+			if ok != tc.ok || delay != tc.expected {
+				t.Errorf("parseRetryAfter(%q) = (%s, %t), expected (%s, %t)", tc.value, delay, ok, tc.expected, tc.ok)
+			}
+		})
+	}
+}
+
+func TestBackoffIsCappedAndJittered(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		delay := policy.backoff(attempt)
+		if delay > policy.MaxDelay {
+			t.Errorf("attempt %d: backoff %s exceeds max delay %s", attempt, delay, policy.MaxDelay)
+		}
+		if attempt == 0 && (delay < 50*time.Millisecond || delay > 100*time.Millisecond) {
+			t.Errorf("attempt 0: backoff %s outside [50ms, 100ms]", delay)
+		}
+	}
+}
+
+func newRetryTestServer(t *testing.T, failures int32, failStatus int, header http.Header) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		if n <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(failStatus)
+			_, _ = w.Write([]byte(`{"message":"try again"}`))
+			return
+		}
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		_, _ = w.Write([]byte(`[{"id":1,"name":"Main Org."}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func TestDoRequestRetriesTransientErrors(t *testing.T) {
+	for _, statusCode := range []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable} {
+		t.Run(http.StatusText(statusCode), func(t *testing.T) {
+			server, calls := newRetryTestServer(t, 2, statusCode, http.Header{"Retry-After": []string{"0"}})
+
+			ctx := context.Background()
+			client, err := NewClient(ctx, server.URL, "admin", "admin", "", WithRetryPolicy(testRetryPolicy))
+			if err != nil {
+				t.Fatalf("NewClient returned error: %v", err)
+			}
+
+			orgs, _, annos, err := client.ListOrganizations(ctx, &PaginationVars{Size: 10})
+			if err != nil {
+				t.Fatalf("ListOrganizations returned error: %v", err)
+			}
+			if len(orgs) != 1 {
+				t.Errorf("expected 1 organization, got %d", len(orgs))
+			}
+			if got := atomic.LoadInt32(calls); got != 3 {
+				t.Errorf("expected 3 calls, got %d", got)
+			}
+
+			rateLimit := &v2.RateLimitDescription{}
+			ok, err := annos.Pick(rateLimit)
+			if err != nil || !ok {
+				t.Fatalf("expected rate limit annotation, got ok=%t err=%v", ok, err)
+			}
+			if rateLimit.Remaining != 42 || rateLimit.Limit != 100 {
+				t.Errorf("unexpected rate limit description: %v", rateLimit)
+			}
+		})
+	}
+}
+
+func TestDoRequestGivesUpAfterMaxRetries(t *testing.T) {
+	server, calls := newRetryTestServer(t, 100, http.StatusTooManyRequests, nil)
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, "admin", "admin", "", WithRetryPolicy(testRetryPolicy))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	_, _, _, err = client.ListOrganizations(ctx, &PaginationVars{Size: 10})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected ResourceExhausted, got %s: %v", status.Code(err), err)
+	}
+	if got := atomic.LoadInt32(calls); got != int32(testRetryPolicy.MaxRetries+1) {
+		t.Errorf("expected %d calls, got %d", testRetryPolicy.MaxRetries+1, got)
+	}
+}
+
+func TestDoRequestDoesNotWaitForLongRetryAfter(t *testing.T) {
+	server, calls := newRetryTestServer(t, 100, http.StatusTooManyRequests, http.Header{"Retry-After": []string{"3600"}})
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, "admin", "admin", "", WithRetryPolicy(testRetryPolicy))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	_, _, _, err = client.ListOrganizations(ctx, &PaginationVars{Size: 10})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("expected 1 call, got %d", got)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.RateLimit == nil {
+		t.Fatalf("expected APIError with rate limit state, got %v", err)
+	}
+	if apiErr.RateLimit.Status != v2.RateLimitDescription_STATUS_OVERLIMIT {
+		t.Errorf("expected STATUS_OVERLIMIT, got %s", apiErr.RateLimit.Status)
+	}
+}
+
+func TestDoRequestDoesNotRetryNonIdempotentMethods(t *testing.T) {
+	server, calls := newRetryTestServer(t, 100, http.StatusServiceUnavailable, nil)
+
+	ctx := context.Background()
+	client, err := NewClient(ctx, server.URL, "admin", "admin", "", WithRetryPolicy(testRetryPolicy))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	_, err = client.doRequest(ctx, http.MethodPost, client.buildResourceURL(ListOrgsPath), nil, map[string]string{"name": "x"}, nil)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("expected 1 call, got %d", got)
+	}
+}
+
+func TestDoRequestRetryHonoursContextCancellation(t *testing.T) {
+	server, _ := newRetryTestServer(t, 100, http.StatusServiceUnavailable, nil)
+
+	policy := RetryPolicy{MaxRetries: 5, BaseDelay: time.Minute, MaxDelay: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	client, err := NewClient(ctx, server.URL, "admin", "admin", "", WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+
+	start := time.Now()
+	_, _, _, err = client.ListOrganizations(ctx, &PaginationVars{Size: 10})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("retry did not stop on context cancellation, took %s", elapsed)
+	}
+}